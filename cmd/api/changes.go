@@ -0,0 +1,68 @@
+// Filename: cmd/api/changes.go
+
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"AWD_FinalProject.ryanarmstrong.net/internal/validator"
+)
+
+// The metadata of a page of changes. next_cursor is what to send as
+// since to get the changes after this page
+type changesMetadata struct {
+	NextCursor string `json:"next_cursor"`
+}
+
+// The listForumChangesHandler for the "GET /v1/forums/changes" endpoint
+// lets partners mirror the directory by pulling the changes since their
+// last sync. The since parameter is either an RFC 3339 time or the
+// next_cursor from a previous response. A change whose forum is null
+// means the forum is gone from the public listing, deleted or hidden
+func (app *application) listForumChangesHandler(w http.ResponseWriter, r *http.Request) {
+	// Initialize a validator
+	v := validator.New()
+	// Get the URL values map
+	qs := r.URL.Query()
+	var cursor int64
+	var since time.Time
+	value := app.readString(qs, "since", "")
+	if value != "" {
+		// A cursor is a plain integer, anything else must be a time
+		if c, err := strconv.ParseInt(value, 10, 64); err == nil && c >= 0 {
+			cursor = c
+		} else if t, err := time.Parse(time.RFC3339, value); err == nil {
+			since = t
+		} else {
+			v.AddError("since", "invalid_since")
+		}
+	}
+	limit := app.readInt(qs, "limit", 100, v)
+	v.Check(limit > 0, "limit", "greater_than_zero")
+	v.Check(limit <= 500, "limit", "maximum", 500)
+	// Check for validation errors
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+	changes, err := app.models.Changes.GetSince(r.Context(), cursor, since, limit)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	// The next cursor points past the last change returned. When there
+	// is nothing new the client keeps polling with the since value it sent
+	next := value
+	for _, change := range changes {
+		next = strconv.FormatInt(change.Cursor, 10)
+		if change.Forum != nil {
+			app.redactForums(r, change.Forum)
+		}
+	}
+	err = app.writeCollection(w, r, http.StatusOK, "changes", changes, changesMetadata{NextCursor: next}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
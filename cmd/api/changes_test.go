@@ -0,0 +1,60 @@
+// Filename: cmd/api/changes_test.go
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"AWD_FinalProject.ryanarmstrong.net/internal/data"
+)
+
+// A page of changes has the shape of every other collection, with the
+// cursor under "metadata", or in X-Next-Cursor when sent bare
+func TestChangesCollection(t *testing.T) {
+	app := newTestApplication(t)
+	changes := []*data.Change{{Cursor: 7, Type: data.ChangeUpdated}}
+
+	rr := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/v1/forums/changes", nil)
+	if err := app.writeCollection(rr, r, http.StatusOK, "changes", changes, changesMetadata{NextCursor: "7"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	var body struct {
+		Changes  []map[string]interface{} `json:"changes"`
+		Metadata map[string]string        `json:"metadata"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Changes) != 1 || body.Metadata["next_cursor"] != "7" {
+		t.Errorf("got %s; want one change and the cursor under metadata", rr.Body.String())
+	}
+	// A hidden or deleted forum is sent as null
+	if forum, ok := body.Changes[0]["forum"]; !ok || forum != nil {
+		t.Errorf("got forum %v; want null", forum)
+	}
+
+	rr = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/v1/forums/changes?envelope=false", nil)
+	if err := app.writeCollection(rr, r, http.StatusOK, "changes", changes, changesMetadata{NextCursor: "7"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := rr.Header().Get("X-Next-Cursor"); got != "7" {
+		t.Errorf("got X-Next-Cursor %q; want \"7\"", got)
+	}
+}
+
+// A since that is neither a cursor nor a time is refused before the feed
+// is read
+func TestListForumChangesQueryErrors(t *testing.T) {
+	srv := newTestApplication(t).routes()
+	for _, query := range []string{"?since=yesterday", "?since=-3", "?limit=0", "?limit=501"} {
+		rr := send(t, srv, http.MethodGet, "/v1/forums/changes"+query, "")
+		if rr.Code != http.StatusUnprocessableEntity {
+			t.Errorf("%s: got status %d; want %d", query, rr.Code, http.StatusUnprocessableEntity)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Filename: cmd/api/compare.go
+
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"AWD_FinalProject.ryanarmstrong.net/internal/data"
+	"AWD_FinalProject.ryanarmstrong.net/internal/validator"
+)
+
+// The most forums that can be compared at once
+const maxCompare = 5
+
+// compareForumsHandler for the "GET /v1/forums/compare" endpoint returns
+// up to five forums side by side, in the order their public ids were given
+func (app *application) compareForumsHandler(w http.ResponseWriter, r *http.Request) {
+	// Initialize a validator
+	v := validator.New()
+	// Get the URL values map
+	qs := r.URL.Query()
+	values := app.readCSV(qs, "ids", []string{}, v)
+	// Drop repeats but keep the first position
+	publicIDs := []string{}
+	seen := make(map[string]bool)
+	for _, value := range values {
+		if !data.PublicIDRX.MatchString(value) {
+			v.AddError("ids", "invalid_public_id")
+			break
+		}
+		if !seen[value] {
+			seen[value] = true
+			publicIDs = append(publicIDs, value)
+		}
+	}
+	v.Check(len(values) > 0, "ids", "required")
+	v.Check(len(publicIDs) <= maxCompare, "ids", "max_entries", maxCompare)
+	// Check for validation errors
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+	// Resolve the public ids, skipping the ones that don't exist
+	ids := []int64{}
+	resolved := make(map[string]int64, len(publicIDs))
+	for _, publicID := range publicIDs {
+		id, err := app.resolvePublicID(r, publicID)
+		if err != nil {
+			if errors.Is(err, data.ErrRecordNotFound) {
+				continue
+			}
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		resolved[publicID] = id
+		ids = append(ids, id)
+	}
+	forums, missingIDs, err := app.models.Forums.GetMany(r.Context(), ids)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	// Tell the client exactly which ids don't exist
+	if len(forums) < len(publicIDs) {
+		gone := make(map[int64]bool, len(missingIDs))
+		for _, id := range missingIDs {
+			gone[id] = true
+		}
+		missing := []string{}
+		for _, publicID := range publicIDs {
+			if id, ok := resolved[publicID]; !ok || gone[id] {
+				missing = append(missing, publicID)
+			}
+		}
+		v.AddError("ids", "ids_not_found", strings.Join(missing, ", "))
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+	app.redactForums(r, forums...)
+	// The listing is read-only so shared caches may hold it briefly
+	headers := make(http.Header)
+	headers.Set("Cache-Control", "public, max-age=60")
+	err = app.writeCollection(w, r, http.StatusOK, "forums", forums, nil, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,35 @@
+// Filename: cmd/api/compare_test.go
+
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// The ids are checked before anything is looked up
+func TestCompareForumsQueryErrors(t *testing.T) {
+	srv := newTestApplication(t).routes()
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"missing", "", "must be provided"},
+		{"serial id", "?ids=42", "public"},
+		{"too many", "?ids=aaaaaaaaaa,bbbbbbbbbb,cccccccccc,dddddddddd,eeeeeeeeee,ffffffffff", "at most 5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := send(t, srv, http.MethodGet, "/v1/forums/compare"+tt.query, "")
+			if rr.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("got status %d; want %d", rr.Code, http.StatusUnprocessableEntity)
+			}
+			if got := errorFields(t, rr)["ids"]; !strings.Contains(got, tt.want) {
+				t.Errorf("got ids error %q; want it to mention %q", got, tt.want)
+			}
+		})
+	}
+
+}
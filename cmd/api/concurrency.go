@@ -0,0 +1,50 @@
+// Filename: cmd/api/concurrency.go
+
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+// How long a request waits for a free slot before it is turned away, and
+// how long the client is then asked to wait before trying again
+const (
+	slotWait       = 2 * time.Second
+	busyRetryAfter = 2
+)
+
+// A semaphore is a set of slots, one per request being served
+type semaphore chan struct{}
+
+// The limitConcurrency() middleware lets at most limit requests through
+// at once. The rest queue for up to slotWait and are then answered with a
+// 503, so a burst is shed at the door instead of piling up on the
+// connection pool. The counters go under name in the metrics. A limit of
+// zero means no limit
+func (app *application) limitConcurrency(name string, limit int, next http.Handler) http.Handler {
+	if limit <= 0 {
+		return next
+	}
+	slots := make(semaphore, limit)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timer := time.NewTimer(slotWait)
+		defer timer.Stop()
+		select {
+		case slots <- struct{}{}:
+		case <-timer.C:
+			metrics.Add(name+"_rejected", 1)
+			app.serverBusyResponse(w, r)
+			return
+		case <-r.Context().Done():
+			metrics.Add("aborted_requests", 1)
+			return
+		}
+		metrics.Add(name+"_in_flight", 1)
+		defer func() {
+			metrics.Add(name+"_in_flight", -1)
+			<-slots
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
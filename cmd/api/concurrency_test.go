@@ -0,0 +1,96 @@
+// Filename: cmd/api/concurrency_test.go
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// The blockingHandler() function gives a handler that reports each
+// request it starts on started, and holds it until release is closed
+func blockingHandler(started chan<- struct{}, release <-chan struct{}) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	})
+}
+
+// With every slot taken a request queues, gets through once one frees
+// up, and is turned away with a 503 if none does within slotWait. This
+// waits out slotWait, so it takes a couple of seconds
+func TestLimitConcurrency(t *testing.T) {
+	app := newTestApplication(t)
+	started, release := make(chan struct{}), make(chan struct{})
+	h := app.limitConcurrency("test_slots", 1, blockingHandler(started, release))
+
+	first := make(chan int)
+	go func() { first <- send(t, h, http.MethodGet, "/", "").Code }()
+	<-started
+	if got := metricValue("test_slots_in_flight"); got != 1 {
+		t.Errorf("got test_slots_in_flight %d; want 1", got)
+	}
+
+	rejected := metricValue("test_slots_rejected")
+	rr := send(t, h, http.MethodGet, "/", "")
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("got status %d with no free slot; want %d", rr.Code, http.StatusServiceUnavailable)
+	}
+	if got := rr.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("got Retry-After %q; want \"2\"", got)
+	}
+	if got := metricValue("test_slots_rejected") - rejected; got != 1 {
+		t.Errorf("test_slots_rejected went up by %d; want 1", got)
+	}
+
+	// A queued request takes the slot as soon as it is handed back
+	second := make(chan int)
+	go func() { second <- send(t, h, http.MethodGet, "/", "").Code }()
+	release <- struct{}{}
+	<-started
+	close(release)
+	if code := <-first; code != http.StatusOK {
+		t.Errorf("got status %d for the first request; want 200", code)
+	}
+	if code := <-second; code != http.StatusOK {
+		t.Errorf("got status %d for the queued request; want 200", code)
+	}
+	if got := metricValue("test_slots_in_flight"); got != 0 {
+		t.Errorf("got test_slots_in_flight %d once done; want 0", got)
+	}
+}
+
+// A client that gives up while queued is let go without an answer
+func TestLimitConcurrencyAborted(t *testing.T) {
+	app := newTestApplication(t)
+	started, release := make(chan struct{}), make(chan struct{})
+	h := app.limitConcurrency("test_aborts", 1, blockingHandler(started, release))
+	go send(t, h, http.MethodGet, "/", "")
+	<-started
+	defer close(release)
+
+	aborted := metricValue("aborted_requests")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+	if rr.Body.Len() != 0 {
+		t.Errorf("got body %q for an aborted request; want none", rr.Body.String())
+	}
+	if got := metricValue("aborted_requests") - aborted; got != 1 {
+		t.Errorf("aborted_requests went up by %d; want 1", got)
+	}
+}
+
+// A limit of zero adds nothing in front of the handler
+func TestLimitConcurrencyOff(t *testing.T) {
+	app := newTestApplication(t)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	h := app.limitConcurrency("test_off", 0, next)
+	if reflect.ValueOf(h).Pointer() != reflect.ValueOf(next).Pointer() {
+		t.Error("got a wrapped handler for a limit of 0")
+	}
+}
@@ -0,0 +1,135 @@
+// Filename: cmd/api/config.go
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"AWD_FinalProject.ryanarmstrong.net/internal/data"
+	"github.com/jackc/pgx/v5"
+)
+
+// Every flag can also be set through an environment variable named after
+// it, e.g. -db-max-open-conns is FORUM_DB_MAX_OPEN_CONNS
+const envPrefix = "FORUM_"
+
+// The envName() function gives the environment variable for a flag
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// The applyEnv() function fills in every flag that wasn't given on the
+// command line from its environment variable. Command line flags win
+func applyEnv(fs *flag.FlagSet) error {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	var problems []error
+	fs.VisitAll(func(f *flag.Flag) {
+		if set[f.Name] {
+			return
+		}
+		value, ok := os.LookupEnv(envName(f.Name))
+		if !ok {
+			return
+		}
+		if err := fs.Set(f.Name, value); err != nil {
+			problems = append(problems, fmt.Errorf("%s: %v", envName(f.Name), err))
+		}
+	})
+	return errors.Join(problems...)
+}
+
+// The isSet() function reports whether a flag was given on the command
+// line or through its environment variable
+func isSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// The splitList() function splits a comma-separated flag value, dropping
+// blank entries
+func splitList(value string) []string {
+	list := []string{}
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
+
+// Validate() checks the whole configuration and reports every problem at
+// once, so a bad deployment fails at startup rather than on a request
+func (cfg config) Validate() error {
+	var problems []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Errorf(format, args...))
+		}
+	}
+	check(cfg.port > 0 && cfg.port <= 65535, "-port must be between 1 and 65535")
+	check(cfg.env == "development" || cfg.env == "staging" || cfg.env == "production",
+		"-env must be one of development, staging or production")
+	check(absoluteURL(cfg.baseURL), "-base-url must be an absolute URL")
+
+	if cfg.db.dsn == "" {
+		problems = append(problems, errors.New("-db-dsn must be provided"))
+	} else if _, err := pgx.ParseConfig(cfg.db.dsn); err != nil {
+		problems = append(problems, errors.New("-db-dsn can't be parsed"))
+	}
+	if cfg.db.readDSN != "" {
+		_, err := pgx.ParseConfig(cfg.db.readDSN)
+		check(err == nil, "-db-read-dsn can't be parsed")
+	}
+	check(cfg.db.maxOpenConns > 0, "-db-max-open-conns must be greater than zero")
+	check(cfg.db.maxIdleConns >= 0, "-db-max-idle-conns must not be negative")
+	_, err := time.ParseDuration(cfg.db.maxIdleTime)
+	check(err == nil, "-db-max-idle-time must be a duration such as 15m")
+	check(cfg.db.slowQuery >= 0, "-slow-query must not be negative")
+
+	check(cfg.cache.size >= 0, "-cache-size must not be negative")
+	check(cfg.cache.ttl > 0, "-cache-ttl must be greater than zero")
+	check(data.MaxInputEntries >= data.ForumModeRule.MaxItems, "-max-input-entries must be at least %d", data.ForumModeRule.MaxItems)
+	check(cfg.list.maxOffset >= 0, "-list-max-offset must not be negative")
+	check(cfg.list.timeout > 0, "-list-timeout must be greater than zero")
+	// The highlight tags are embedded in a quoted ts_headline() option
+	check(!strings.Contains(cfg.search.highlightStart+cfg.search.highlightStop, `"`),
+		"search highlight tags must not contain double quotes")
+
+	if cfg.tracing.endpoint != "" {
+		check(absoluteURL(cfg.tracing.endpoint), "-otel-endpoint must be an absolute URL")
+	}
+	if cfg.errorWebhook != "" {
+		check(absoluteURL(cfg.errorWebhook), "-error-webhook must be an absolute URL")
+	}
+	if cfg.importer.url != "" {
+		check(absoluteURL(cfg.importer.url), "-import-url must be an absolute URL")
+		// The scheduler is the only way an import runs
+		check(cfg.importer.interval != 0, "-import-interval must be greater than zero when -import-url is set")
+	}
+	check(cfg.importer.interval >= 0, "-import-interval must not be negative")
+	check(cfg.concurrency.max >= 0, "-max-concurrent must not be negative")
+	check(cfg.concurrency.search >= 0, "-max-concurrent-search must not be negative")
+	check(cfg.tables.limit > 0, "-table-size-limit must be greater than zero")
+	check(cfg.tracing.sampleRatio >= 0 && cfg.tracing.sampleRatio <= 1, "-otel-sample-ratio must be between 0 and 1")
+	return errors.Join(problems...)
+}
+
+// The absoluteURL() function reports whether s is a URL with a scheme and host
+func absoluteURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
@@ -0,0 +1,161 @@
+// Filename: cmd/api/deprecations.go
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// A deprecation is a part of the API we mean to remove. Since is when it
+// was deprecated and Sunset is when it may stop working
+type deprecation struct {
+	message string
+	since   time.Time
+	sunset  time.Time
+}
+
+// The deprecations registry. Entries are looked up by name when a request
+// or response uses them, so the dates only ever live here
+var deprecations = map[string]deprecation{
+	"mode_string": {
+		message: "sending mode as a comma-separated string is deprecated, send an array of strings",
+		since:   time.Date(2026, time.October, 14, 0, 0, 0, 0, time.UTC),
+		sunset:  time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC),
+	},
+	"phone_flat": {
+		message: "the flat phone field is deprecated, send phones.primary instead",
+		since:   time.Date(2026, time.October, 14, 0, 0, 0, 0, time.UTC),
+		sunset:  time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC),
+	},
+	"phone_flat_output": {
+		message: "the flat phone field in forums is deprecated, read phones.primary instead",
+		since:   time.Date(2026, time.October, 14, 0, 0, 0, 0, time.UTC),
+		sunset:  time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC),
+	},
+}
+
+// The body fields whose comma-separated string form is deprecated
+var deprecatedStringFields = map[string]string{
+	"mode": "mode_string",
+}
+
+// The body fields that are deprecated in any form
+var deprecatedFields = map[string]string{
+	"phone": "phone_flat",
+}
+
+// deprecationUses counts how often each deprecation is hit. It shows up
+// in GET /v1/metrics under "deprecations"
+var deprecationUses = func() *expvar.Map {
+	uses := new(expvar.Map)
+	metrics.Set("deprecations", uses)
+	return uses
+}()
+
+// The usedDeprecations type collects the deprecations a request ran into
+// so writeJSON() can report them
+type usedDeprecations struct {
+	mu    sync.Mutex
+	names []string
+}
+
+type contextKey string
+
+const deprecationsContextKey = contextKey("deprecations")
+
+// The trackDeprecations() middleware gives each request somewhere to
+// collect its deprecations
+func (app *application) trackDeprecations(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), deprecationsContextKey, &usedDeprecations{})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// The deprecated() method records that the request used the named
+// deprecation. Clients sending X-Strict: true get its message back as an
+// error instead, which the handler turns into a 400
+func (app *application) deprecated(r *http.Request, name string) error {
+	d, ok := deprecations[name]
+	if !ok {
+		panic("unknown deprecation " + name)
+	}
+	deprecationUses.Add(name, 1)
+	if strict, _ := strconv.ParseBool(r.Header.Get("X-Strict")); strict {
+		return errors.New(d.message)
+	}
+	used, ok := r.Context().Value(deprecationsContextKey).(*usedDeprecations)
+	if !ok {
+		return nil
+	}
+	used.mu.Lock()
+	defer used.mu.Unlock()
+	for _, existing := range used.names {
+		if existing == name {
+			return nil
+		}
+	}
+	used.names = append(used.names, name)
+	return nil
+}
+
+// The checkDeprecatedInput() method looks through the top level of a JSON
+// body for deprecated input forms
+func (app *application) checkDeprecatedInput(r *http.Request, body []byte) error {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(body, &fields) != nil {
+		return nil
+	}
+	for field, name := range deprecatedFields {
+		if _, ok := fields[field]; ok {
+			if err := app.deprecated(r, name); err != nil {
+				return err
+			}
+		}
+	}
+	for field, name := range deprecatedStringFields {
+		if raw, ok := fields[field]; ok && len(raw) > 0 && raw[0] == '"' {
+			if err := app.deprecated(r, name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// The deprecationWarnings() method sets the Deprecation and Sunset
+// headers for the deprecations the request used and returns their
+// messages. With several in play the earliest dates are sent
+func (app *application) deprecationWarnings(w http.ResponseWriter, r *http.Request) []string {
+	used, ok := r.Context().Value(deprecationsContextKey).(*usedDeprecations)
+	if !ok {
+		return nil
+	}
+	used.mu.Lock()
+	defer used.mu.Unlock()
+	if len(used.names) == 0 {
+		return nil
+	}
+	var since, sunset time.Time
+	warnings := make([]string, 0, len(used.names))
+	for _, name := range used.names {
+		d := deprecations[name]
+		if since.IsZero() || d.since.Before(since) {
+			since = d.since
+		}
+		if sunset.IsZero() || d.sunset.Before(sunset) {
+			sunset = d.sunset
+		}
+		warnings = append(warnings, d.message)
+	}
+	w.Header().Set("Deprecation", "@"+strconv.FormatInt(since.Unix(), 10))
+	w.Header().Set("Sunset", sunset.Format(http.TimeFormat))
+	return warnings
+}
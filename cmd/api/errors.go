@@ -1,60 +1,183 @@
+// Filename: cmd/api/errors.go
+
 package main
 
 import (
-	"fmt"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+
+	"AWD_FinalProject.ryanarmstrong.net/internal/i18n"
+	"AWD_FinalProject.ryanarmstrong.net/internal/validator"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// The kinds of error serverErrorResponse() tells apart
+type errKind int
+
+const (
+	// A fault in the application itself
+	kindInternal errKind = iota
+	// The database can't be reached or refuses connections
+	kindUnavailable
 )
 
+// How long clients are asked to wait when the database is down
+const unavailableRetryAfter = 10
+
+// The errorKind() function sorts an error into an errKind. Connection
+// failures at the driver, dial or server level mean the database is
+// unavailable, everything else is treated as our own fault
+func errorKind(err error) errKind {
+	var connectErr *pgconn.ConnectError
+	var netErr *net.OpError
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, driver.ErrBadConn), errors.Is(err, sql.ErrConnDone):
+		return kindUnavailable
+	case errors.As(err, &connectErr), errors.As(err, &netErr):
+		return kindUnavailable
+	case errors.As(err, &pgErr):
+		// Class 08 is connection exceptions; 57P01 to 57P03 are the server
+		// shutting down or still starting up
+		if strings.HasPrefix(pgErr.Code, "08") || pgErr.Code == "57P01" || pgErr.Code == "57P02" || pgErr.Code == "57P03" {
+			return kindUnavailable
+		}
+	}
+	return kindInternal
+}
+
 func (app *application) logError(r *http.Request, err error) {
 	app.logger.Println(err)
 }
 
+// The translator() method picks the language for our messages from the
+// request's Accept-Language header, defaulting to English
+func (app *application) translator(r *http.Request) i18n.Translator {
+	return i18n.Negotiate(r.Header.Get("Accept-Language"))
+}
+
 // We want to send JSON-formatted error messages
 func (app *application) errorResponse(w http.ResponseWriter, r *http.Request, status int, message interface{}) {
 	// Create the JSON response
 	env := envelope{"error": message}
-	err := app.writeJSON(w, status, env, nil)
+	err := app.writeJSON(w, r, status, env, nil)
 	if err != nil {
 		app.logError(r, err)
 		w.WriteHeader(http.StatusInternalServerError)
 	}
 }
 
-// Server error response
+// Server error response. When the database is down the client gets a 503
+// so operators can tell an outage from a bug
 func (app *application) serverErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
-	// We log the error
-	app.logError(r, err)
+	// A query cancelled because the client disconnected isn't a fault of
+	// ours, so only log errors for clients that are still there
+	if r.Context().Err() == nil {
+		app.logError(r, err)
+	}
+	if errorKind(err) == kindUnavailable {
+		app.serviceUnavailableResponse(w, r)
+		return
+	}
+	if r.Context().Err() == nil {
+		app.reportError(err, requestMeta(r))
+	}
 	// Prepare a message with the error
-	message := "the server encountered a problem and could not process the request"
+	message := app.translator(r).T("server_error")
 	app.errorResponse(w, r, http.StatusInternalServerError, message)
 }
 
 // The not found response
 func (app *application) notFoundResponse(w http.ResponseWriter, r *http.Request) {
 	// Create our message
-	message := "the requested resource could not be found"
+	message := app.translator(r).T("not_found")
 	app.errorResponse(w, r, http.StatusNotFound, message)
 }
 
 // A method not allowed response
 func (app *application) methodNotAllowedResponse(w http.ResponseWriter, r *http.Request) {
 	// Create our message
-	message := fmt.Sprintf("the %s method is not supported for this resource", r.Method)
+	message := app.translator(r).T("method_not_allowed", r.Method)
 	app.errorResponse(w, r, http.StatusNotFound, message)
 }
 
-// User provided a bad request
+// User provided a bad request. A body that sets read-only fields is
+// reported as a failed validation keyed on each of them
 func (app *application) badRequestResponse(w http.ResponseWriter, r *http.Request, err error) {
+	var readOnly *readOnlyError
+	if errors.As(err, &readOnly) {
+		v := validator.New()
+		for _, field := range readOnly.fields {
+			v.AddError(field, "read_only")
+		}
+		app.failedValidationResponse(w, r, v)
+		return
+	}
 	app.errorResponse(w, r, http.StatusBadRequest, err.Error())
 }
 
 // validation error
-func (app *application) failedValidationResponse(w http.ResponseWriter, r *http.Request, errors map[string]string) {
+// The field names stay as keys while the messages are translated
+func (app *application) failedValidationResponse(w http.ResponseWriter, r *http.Request, v *validator.Validator) {
+	t := app.translator(r)
+	errors := make(map[string]string, len(v.Errors))
+	for field, key := range v.Errors {
+		errors[field] = t.T(key, v.Args[field]...)
+	}
 	app.errorResponse(w, r, http.StatusUnprocessableEntity, errors)
 }
 
+// A query ran past its statement timeout
+func (app *application) queryTimeoutResponse(w http.ResponseWriter, r *http.Request) {
+	message := app.translator(r).T("query_timeout")
+	app.errorResponse(w, r, http.StatusServiceUnavailable, message)
+}
+
+// The database is unreachable. The code lets clients tell this apart
+// from other 503s
+func (app *application) serviceUnavailableResponse(w http.ResponseWriter, r *http.Request) {
+	metrics.Add("database_unavailable", 1)
+	headers := make(http.Header)
+	headers.Set("Retry-After", strconv.Itoa(unavailableRetryAfter))
+	env := envelope{
+		"error": app.translator(r).T("service_unavailable"),
+		"code":  "service_unavailable",
+	}
+	err := app.writeJSON(w, r, http.StatusServiceUnavailable, env, headers)
+	if err != nil {
+		app.logError(r, err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// The request body is of a type the route doesn't take
+func (app *application) unsupportedMediaTypeResponse(w http.ResponseWriter, r *http.Request, accepts []string) {
+	message := app.translator(r).T("unsupported_media_type", strings.Join(accepts, ", "))
+	app.errorResponse(w, r, http.StatusUnsupportedMediaType, message)
+}
+
+// Every slot is taken and none came free in time
+func (app *application) serverBusyResponse(w http.ResponseWriter, r *http.Request) {
+	headers := make(http.Header)
+	headers.Set("Retry-After", strconv.Itoa(busyRetryAfter))
+	env := envelope{
+		"error": app.translator(r).T("server_busy"),
+		"code":  "server_busy",
+	}
+	err := app.writeJSON(w, r, http.StatusServiceUnavailable, env, headers)
+	if err != nil {
+		app.logError(r, err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
 // Edit Conflict error
 func (app *application) editConflictResponse(w http.ResponseWriter, r *http.Request) {
-	message := "unable to update the record due to an edit conflict, please try again"
+	message := app.translator(r).T("edit_conflict")
 	app.errorResponse(w, r, http.StatusConflict, message)
 }
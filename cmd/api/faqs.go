@@ -0,0 +1,217 @@
+// Filename: cmd/api/faqs.go
+
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"AWD_FinalProject.ryanarmstrong.net/internal/data"
+	"AWD_FinalProject.ryanarmstrong.net/internal/validator"
+)
+
+// createFAQHandler for the "POST /v1/forums/:id/faqs" endpoint adds a FAQ
+// to the end of the forum's list
+func (app *application) createFAQHandler(w http.ResponseWriter, r *http.Request) {
+	forumID, err := app.readForumID(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	var input struct {
+		Question string `json:"question"`
+		Answer   string `json:"answer"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	faq := &data.FAQ{
+		ForumID:  forumID,
+		Question: input.Question,
+		Answer:   input.Answer,
+	}
+	v := validator.New()
+	data.ValidateFAQ(v, faq)
+	app.moderateFAQ(v, faq)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+	err = app.models.FAQs.Insert(r.Context(), faq)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrTooManyFAQs):
+			v.AddError("faqs", "max_entries", data.MaxFAQs)
+			app.failedValidationResponse(w, r, v)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeResource(w, r, http.StatusCreated, "faq", faq, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateFAQHandler for the "PATCH /v1/forums/:id/faqs/:faq_id" endpoint
+// changes the question or answer of a FAQ
+func (app *application) updateFAQHandler(w http.ResponseWriter, r *http.Request) {
+	forumID, err := app.readForumID(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	id, err := app.readInt64Param(r, "faq_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	faq, err := app.models.FAQs.Get(r.Context(), forumID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	// Pointers tell us which fields the client left out
+	var input struct {
+		Question *string `json:"question"`
+		Answer   *string `json:"answer"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if input.Question != nil {
+		faq.Question = *input.Question
+	}
+	if input.Answer != nil {
+		faq.Answer = *input.Answer
+	}
+	v := validator.New()
+	data.ValidateFAQ(v, faq)
+	app.moderateFAQ(v, faq)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+	err = app.models.FAQs.Update(r.Context(), faq)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeResource(w, r, http.StatusOK, "faq", faq, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteFAQHandler for the "DELETE /v1/forums/:id/faqs/:faq_id" endpoint
+// removes a FAQ. The FAQs after it move up a place
+func (app *application) deleteFAQHandler(w http.ResponseWriter, r *http.Request) {
+	forumID, err := app.readForumID(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	id, err := app.readInt64Param(r, "faq_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	err = app.models.FAQs.Delete(r.Context(), forumID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeMessage(w, r, http.StatusOK, "faq successfully deleted")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// reorderFAQsHandler for the "PATCH /v1/forums/:id/faqs/order" endpoint
+// takes every FAQ id of the forum in the order they should appear
+func (app *application) reorderFAQsHandler(w http.ResponseWriter, r *http.Request) {
+	forumID, err := app.readForumID(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	var input struct {
+		IDs data.IDList `json:"ids"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	v := validator.New()
+	v.Check(input.IDs != nil, "ids", "required")
+	v.Check(len(input.IDs) <= data.MaxFAQs, "ids", "max_entries", data.MaxFAQs)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+	err = app.models.FAQs.Reorder(r.Context(), forumID, []int64(input.IDs))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrFAQOrder):
+			v.AddError("ids", "faq_order")
+			app.failedValidationResponse(w, r, v)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	faqs, err := app.models.FAQs.GetAll(r.Context(), forumID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeCollection(w, r, http.StatusOK, "faqs", faqs, nil, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,52 @@
+// Filename: cmd/api/features.go
+
+package main
+
+import (
+	"flag"
+	"net/http"
+)
+
+// The features type says which optional areas of the API are switched on.
+// A disabled area has no routes, runs no background work and never
+// touches its tables. Its migrations still have to run, they are numbered
+// in one sequence with everyone else's
+type features struct {
+	faqs    bool
+	views   bool
+	feed    bool
+	search  bool
+	compare bool
+}
+
+// The registerFlags() method adds an -enable-... flag for every area.
+// Everything is on by default
+func (f *features) registerFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&f.faqs, "enable-faqs", true, "Serve forum FAQ entries (needs the forum_faqs table)")
+	fs.BoolVar(&f.views, "enable-views", true, "Count forum views and offer the trending sort (needs the forum_views table)")
+	fs.BoolVar(&f.feed, "enable-feed", true, "Serve the Atom feed of recently added forums")
+	fs.BoolVar(&f.search, "enable-search", true, "Serve GET /v1/search")
+	fs.BoolVar(&f.compare, "enable-compare", true, "Serve GET /v1/forums/compare")
+}
+
+// The list() method gives the state of every area by name
+func (f features) list() map[string]bool {
+	return map[string]bool{
+		"faqs":    f.faqs,
+		"views":   f.views,
+		"feed":    f.feed,
+		"search":  f.search,
+		"compare": f.compare,
+	}
+}
+
+// featuresHandler for the "GET /v1/features" endpoint tells the front end
+// which areas are switched on so it can hide the rest
+func (app *application) featuresHandler(w http.ResponseWriter, r *http.Request) {
+	headers := make(http.Header)
+	headers.Set("Cache-Control", "public, max-age=300")
+	err := app.writeResource(w, r, http.StatusOK, "features", app.config.features.list(), headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
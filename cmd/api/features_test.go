@@ -0,0 +1,124 @@
+// Filename: cmd/api/features_test.go
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// A feature route, and the handler that serves it when the area is on
+type featureRoute struct {
+	method  string
+	path    string
+	handler string
+}
+
+var featureRoutes = map[string][]featureRoute{
+	"faqs": {
+		{http.MethodPost, "/v1/forums/1/faqs", "createFAQHandler"},
+		{http.MethodPatch, "/v1/forums/1/faqs/2", "updateFAQHandler"},
+		{http.MethodPatch, "/v1/forums/1/faqs/order", "reorderFAQsHandler"},
+		{http.MethodDelete, "/v1/forums/1/faqs/2", "deleteFAQHandler"},
+	},
+	"feed": {
+		{http.MethodGet, "/v1/forums/feed.atom", "recentForumsFeedHandler"},
+	},
+	"search": {
+		{http.MethodGet, "/v1/search", "searchHandler"},
+	},
+	"compare": {
+		{http.MethodGet, "/v1/forums/compare", "compareForumsHandler"},
+	},
+}
+
+// Booting with different -enable-... flags should switch each area's
+// routes on and off, and GET /v1/features should say which are on
+func TestFeatureFlags(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want map[string]bool
+	}{
+		{
+			name: "defaults",
+			want: map[string]bool{"faqs": true, "views": true, "feed": true, "search": true, "compare": true},
+		},
+		{
+			name: "no faqs",
+			args: []string{"-enable-faqs=false"},
+			want: map[string]bool{"faqs": false, "views": true, "feed": true, "search": true, "compare": true},
+		},
+		{
+			name: "no feed or compare",
+			args: []string{"-enable-feed=false", "-enable-compare=false"},
+			want: map[string]bool{"faqs": true, "views": true, "feed": false, "search": true, "compare": false},
+		},
+		{
+			name: "no search or views",
+			args: []string{"-enable-search=false", "-enable-views=false"},
+			want: map[string]bool{"faqs": true, "views": false, "feed": true, "search": false, "compare": true},
+		},
+		{
+			name: "everything off",
+			args: []string{"-enable-faqs=false", "-enable-views=false", "-enable-feed=false", "-enable-search=false", "-enable-compare=false"},
+			want: map[string]bool{"faqs": false, "views": false, "feed": false, "search": false, "compare": false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t, tt.args...)
+			srv := app.routes()
+			stubbed := app.router(stubRoutes(app.routeTable()))
+
+			got := readFeatures(t, srv)
+			for name, on := range tt.want {
+				if got[name] != on {
+					t.Errorf("GET /v1/features says %s is %t; want %t", name, got[name], on)
+				}
+			}
+			for area, routes := range featureRoutes {
+				for _, fr := range routes {
+					if tt.want[area] {
+						checkRoute(t, stubbed, fr.method, fr.path, fr.handler)
+						continue
+					}
+					// A switched-off area answers like any unknown path.
+					// These never get as far as the models
+					rr := httptest.NewRecorder()
+					srv.ServeHTTP(rr, httptest.NewRequest(fr.method, fr.path, nil))
+					if rr.Code != http.StatusNotFound {
+						t.Errorf("%s %s with %s off: got status %d; want %d", fr.method, fr.path, area, rr.Code, http.StatusNotFound)
+					}
+				}
+			}
+			// Without views there is nothing to sort trending forums by
+			if !tt.want["views"] {
+				rr := httptest.NewRecorder()
+				srv.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/forums?sort=-trending", nil))
+				if rr.Code != http.StatusUnprocessableEntity {
+					t.Errorf("sort=-trending with views off: got status %d; want %d", rr.Code, http.StatusUnprocessableEntity)
+				}
+			}
+		})
+	}
+}
+
+// The readFeatures() function asks GET /v1/features which areas are on
+func readFeatures(t *testing.T, srv http.Handler) map[string]bool {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	srv.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/features", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("GET /v1/features: got status %d; want %d", rr.Code, http.StatusOK)
+	}
+	var body struct {
+		Features map[string]bool `json:"features"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	return body.Features
+}
@@ -0,0 +1,124 @@
+// Filename: cmd/api/feed.go
+
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// How many forums the Atom feed carries
+const feedSize = 50
+
+// The date in our tag URIs. It only has to be a day on which we owned the
+// host name and must never change, or every feed reader sees new entries
+const feedTagDate = "2022"
+
+// The atom* types are the parts of an Atom (RFC 4287) document we use
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID        string   `xml:"id"`
+	Title     string   `xml:"title"`
+	Link      atomLink `xml:"link"`
+	Published string   `xml:"published"`
+	Updated   string   `xml:"updated"`
+	Summary   string   `xml:"summary"`
+}
+
+// recentForumsFeedHandler for the "GET /v1/forums/feed.atom" endpoint
+// returns the newest forums as an Atom feed
+func (app *application) recentForumsFeedHandler(w http.ResponseWriter, r *http.Request) {
+	entries, err := app.models.Forums.GetRecent(r.Context(), feedSize)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	// The feed changes when an entry is added, updated or drops out, so
+	// the ETag covers every entry rather than only the newest one
+	var updated time.Time
+	hash := fnv.New64a()
+	for _, entry := range entries {
+		if entry.UpdatedAt.After(updated) {
+			updated = entry.UpdatedAt
+		}
+		fmt.Fprintf(hash, "%d:%d;", entry.Forum.ID, entry.UpdatedAt.UnixNano())
+	}
+	etag := fmt.Sprintf(`"%x"`, hash.Sum64())
+	w.Header().Set("Cache-Control", "public, max-age=600")
+	w.Header().Set("ETag", etag)
+	if match := r.Header.Get("If-None-Match"); match != "" && strings.Contains(match, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	// An empty feed still needs an updated date
+	if updated.IsZero() {
+		updated = app.clock.Now()
+	}
+	host := feedHost(app.config.baseURL)
+	feed := atomFeed{
+		ID:      fmt.Sprintf("tag:%s,%s:forums", host, feedTagDate),
+		Title:   "Recently added forums",
+		Updated: updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: app.urlFor("/v1/forums/feed.atom")},
+		},
+		Author: atomAuthor{Name: "Forum Directory"},
+	}
+	for _, entry := range entries {
+		forum := entry.Forum
+		summary := fmt.Sprintf("%s. Mode: %s. %s", forum.Level, strings.Join(forum.Mode, ", "), forum.Address)
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:        fmt.Sprintf("tag:%s,%s:forum/%s", host, feedTagDate, forum.PublicID),
+			Title:     forum.Name,
+			Link:      atomLink{Rel: "alternate", Type: "application/json", Href: app.urlFor("/v1/forums/%s", forum.PublicID)},
+			Published: forum.CreatedAt.UTC().Format(time.RFC3339),
+			Updated:   entry.UpdatedAt.UTC().Format(time.RFC3339),
+			Summary:   summary,
+		})
+	}
+	// Skip the encoding if the client has already given up
+	if r.Context().Err() != nil {
+		metrics.Add("aborted_requests", 1)
+		return
+	}
+	out, err := xml.MarshalIndent(feed, "", "\t")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	writeBody(w, r, http.StatusOK, []byte(xml.Header), out)
+}
+
+// The feedHost() function gives the host name used in our tag URIs
+func feedHost(baseURL string) string {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Hostname() == "" {
+		return "localhost"
+	}
+	return u.Hostname()
+}
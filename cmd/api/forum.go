@@ -3,11 +3,15 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"AWD_FinalProject.ryanarmstrong.net/internal/data"
+	"AWD_FinalProject.ryanarmstrong.net/internal/moderation"
 	"AWD_FinalProject.ryanarmstrong.net/internal/validator"
 )
 
@@ -15,14 +19,24 @@ import (
 func (app *application) createForumHandler(w http.ResponseWriter, r *http.Request) {
 	// Our target decode destination
 	var input struct {
-		Name    string   `json:"name"`
-		Level   string   `json:"level"`
-		Contact string   `json:"contact"`
-		Phone   string   `json:"phone"`
-		Email   string   `json:"email"`
-		Website string   `json:"website"`
-		Address string   `json:"address"`
-		Mode    []string `json:"mode"`
+		Name    string `json:"name"`
+		Level   string `json:"level"`
+		Contact string `json:"contact"`
+		Phone   string `json:"phone"`
+		// The numbers in full. The flat phone above is the older form
+		Phones  *data.Phones `json:"phones"`
+		Email   string       `json:"email"`
+		Website string       `json:"website"`
+		Address string       `json:"address"`
+		Mode    data.Modes   `json:"mode"`
+		// Forums teach in English unless the client says otherwise
+		Languages data.Languages `json:"languages"`
+		// Contact details are public unless the client says otherwise
+		PublicContact *bool `json:"public_contact"`
+		// Capacity data is optional
+		StudentCapacity   *int32 `json:"student_capacity"`
+		CurrentEnrollment *int32 `json:"current_enrollment"`
+		EnrollmentPrivate bool   `json:"enrollment_private"`
 	}
 	// Initialize a new json.Decoder instance
 	err := app.readJSON(w, r, &input)
@@ -36,49 +50,97 @@ func (app *application) createForumHandler(w http.ResponseWriter, r *http.Reques
 		Name:    input.Name,
 		Level:   input.Level,
 		Contact: input.Contact,
-		Phone:   input.Phone,
+		Phones:  data.Phones{Primary: input.Phone},
 		Email:   input.Email,
 		Website: input.Website,
 		Address: input.Address,
 		Mode:    input.Mode,
+		// Default to English and public contact details
+		Languages:         data.DefaultLanguages,
+		PublicContact:     true,
+		StudentCapacity:   input.StudentCapacity,
+		CurrentEnrollment: input.CurrentEnrollment,
+		EnrollmentPrivate: input.EnrollmentPrivate,
+	}
+	if input.Languages != nil {
+		forum.Languages = input.Languages
+	}
+	if input.Phones != nil {
+		forum.Phones = *input.Phones
+	}
+	forum.Phones.Normalize()
+	if input.PublicContact != nil {
+		forum.PublicContact = *input.PublicContact
 	}
 	// Initialize a new Validator instance
 	v := validator.New()
+	v.Check(input.Phone == "" || input.Phones == nil, "phones", "phone_conflict")
 
 	// Check the map to determine if there were any validation errors
-	if data.ValidateForum(v, forum); !v.Valid() {
-		app.failedValidationResponse(w, r, v.Errors)
+	data.ValidateForum(v, forum)
+	app.moderate(v, forum)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
 		return
 	}
 
 	// Create a Forum
-	err = app.models.Forums.Insert(forum)
+	err = app.models.Forums.Insert(r.Context(), forum)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
+		return
 	}
+	// Forget any recent miss on this id so the new forum shows at once
+	app.misses.Invalidate(forum.ID)
+	app.publicMisses.Invalidate(forum.PublicID)
 
 	// Create a Location header for the newly created resource/Forum
 	headers := make(http.Header)
-	headers.Set("Location", fmt.Sprintf("/v1/forums/%d", forum.ID))
+	headers.Set("Location", fmt.Sprintf("/v1/forums/%s", forum.PublicID))
 	// Write the JSON response with 201 - Created status code with the body
 	// being the Forum data and the header being the headers map
-	err = app.writeJSON(w, http.StatusCreated, envelope{"forum": forum}, headers)
+	app.redactForums(r, forum)
+	err = app.writeResource(w, r, http.StatusCreated, "forum", forum, headers)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
 
+// How long an id that wasn't found is answered from memory
+const missTTL = 30 * time.Second
+
+// How long a public id to serial id lookup is remembered. Public ids
+// never change, so this only bounds memory for deleted forums
+const publicIDTTL = time.Hour
+
 // showForumHandler for the "Post /v1/forums/:id" endpoint
 func (app *application) showForumHandler(w http.ResponseWriter, r *http.Request) {
-	id, err := app.readIDParam(r)
+	id, err := app.readForumID(r)
 	if err != nil {
-		app.notFoundResponse(w, r)
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
-	// Fetch the specific forum
-	forum, err := app.models.Forums.Get(id)
-	// Handle errors
+	// Related lists the client wants alongside the forum
+	v := validator.New()
+	include := app.readCSV(r.URL.Query(), "include", []string{}, v)
+	for _, value := range include {
+		v.Check(app.config.features.faqs && validator.In(value, "faqs"), "include", "invalid_include", "faqs")
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+	// Serve the forum from the cache when we have a fresh copy. Reads go
+	// to the primary: a lagging replica could hand us the row from before
+	// an update, and once cached that stale copy would be served for the
+	// whole TTL
+	forum, hit, err := app.cachedForum(r.Context(), id, app.models.Forums.GetPrimary)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -88,23 +150,191 @@ func (app *application) showForumHandler(w http.ResponseWriter, r *http.Request)
 		}
 		return
 	}
-	// Write the data returned by Get()
-	err = app.writeJSON(w, http.StatusOK, envelope{"forum": forum}, nil)
+	headers := make(http.Header)
+	headers.Set("X-Cache", "MISS")
+	if hit {
+		headers.Set("X-Cache", "HIT")
+	}
+	if validator.In("faqs", include...) {
+		forum.FAQs, err = app.models.FAQs.GetAll(r.Context(), id)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	app.redactForums(r, &forum)
+	err = app.writeResource(w, r, http.StatusOK, "forum", forum, headers)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
+		return
+	}
+	// Count the view once the forum has been sent. A HEAD request is a
+	// monitor checking the forum is there, not someone reading it
+	if app.config.features.views && r.Method != http.MethodHead {
+		app.views.add(id)
+	}
+}
+
+// The cachedForum() method returns the forum from the cache, or reads it
+// with fetch and caches it. hit reports whether the cache answered. An
+// id we just failed to find is answered without calling fetch
+func (app *application) cachedForum(ctx context.Context, id int64, fetch func(context.Context, int64) (*data.Forum, error)) (data.Forum, bool, error) {
+	if forum, ok := app.cache.Get(id); ok {
+		return forum, true, nil
+	}
+	if _, missed := app.misses.Get(id); missed {
+		metrics.Add("negative_cache_hits", 1)
+		return data.Forum{}, false, data.ErrRecordNotFound
+	}
+	// Take the stamps before reading so an update or create that lands
+	// while we are reading can't leave a stale entry behind
+	stamp := app.cache.Stamp()
+	missStamp := app.misses.Stamp()
+	fetched, err := fetch(ctx, id)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			app.misses.Set(id, struct{}{}, missStamp)
+		}
+		return data.Forum{}, false, err
 	}
+	app.cache.Set(id, *fetched, stamp)
+	return *fetched, false, nil
 }
 
 func (app *application) updateForumHandler(w http.ResponseWriter, r *http.Request) {
 	// This method does a partial replacement
 	// Get the id for the forum that needs updating
-	id, err := app.readIDParam(r)
+	id, err := app.readForumID(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	// Fetch the original record from the primary so a lagging replica
+	// doesn't hand us an old version
+	forum, err := app.models.Forums.GetPrimary(r.Context(), id)
+	// Handle errors
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	// A merge patch body is applied to the whole representation, anything
+	// else is read into the pointer struct of patchForum()
+	v := validator.New()
+	if mergePatchRequest(r) {
+		err = app.mergePatchForum(w, r, forum)
+	} else {
+		err = app.patchForum(w, r, forum, v)
+	}
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Perform validation on the updated Forum. If validation fails, then
+	// we send a 422 - Unprocessable Entity response to the client
+	// Check the map to determine if there were any validation errors
+	data.ValidateForum(v, forum)
+	app.moderate(v, forum)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+	// Pass the updated Forum record to the Update() method
+	err = app.models.Forums.Update(r.Context(), forum)
+	// Drop any cached copy whether or not the update went through
+	app.cache.Invalidate(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	// Write the data returned by Update()
+	app.redactForums(r, forum)
+	err = app.writeResource(w, r, http.StatusOK, "forum", forum, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteForumHandler(w http.ResponseWriter, r *http.Request) {
+	// Get the id for the forum that needs to be deleted
+	id, err := app.readForumID(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	// With ?dry_run=true the delete is run and rolled back to preview it
+	v := validator.New()
+	dryRun := app.readBool(r.URL.Query(), "dry_run", false, v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+	// Delete the Forum from the database. Send a 404 Not Found status code to the
+	// client if there is no matching record
+	impact, err := app.models.Forums.Delete(r.Context(), id, dryRun)
+	if !dryRun {
+		app.cache.Invalidate(id)
+	}
+	// Handle errors
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if dryRun {
+		err = app.writeResource(w, r, http.StatusOK, "impact", impact, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	// Return 200 Status OK to the client with a successful message
+	err = app.writeMessage(w, r, http.StatusOK, "forum successfully deleted")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// renewForumHandler for the "POST /v1/forums/:id/renew" endpoint extends
+// the listing for another year
+func (app *application) renewForumHandler(w http.ResponseWriter, r *http.Request) {
+	// Get the id for the forum that needs renewing
+	id, err := app.readForumID(r)
 	if err != nil {
-		app.notFoundResponse(w, r)
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
-	// Fetch the original record from the database
-	forum, err := app.models.Forums.Get(id)
+	// Fetch the original record from the primary so a lagging replica
+	// doesn't hand us an old version
+	forum, err := app.models.Forums.GetPrimary(r.Context(), id)
 	// Handle errors
 	if err != nil {
 		switch {
@@ -115,26 +345,64 @@ func (app *application) updateForumHandler(w http.ResponseWriter, r *http.Reques
 		}
 		return
 	}
+	// Push the expiry out, this also bumps the version
+	err = app.models.Forums.Renew(r.Context(), forum)
+	app.cache.Invalidate(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	// Write the renewed forum
+	app.redactForums(r, forum)
+	err = app.writeResource(w, r, http.StatusOK, "forum", forum, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The patchForum() method reads a PATCH body of plain JSON and applies
+// the fields it holds to forum. A field that is absent or null is left
+// as it is. Conflicts between fields are added to v
+func (app *application) patchForum(w http.ResponseWriter, r *http.Request, forum *data.Forum, v *validator.Validator) error {
 	// Create an input struct to hold data read in from the client
 	// We update input struct to use pointers because pointers have a
 	// default value of nil
 	// If a field remains nil then we know the client did not update it
 	var input struct {
-		Name    *string  `json:"name"`
-		Level   *string  `json:"level"`
-		Contact *string  `json:"contact"`
-		Phone   *string  `json:"phone"`
-		Email   *string  `json:"email"`
-		Website *string  `json:"website"`
-		Address *string  `json:"address"`
-		Mode    []string `json:"mode"`
+		Name    *string `json:"name"`
+		Level   *string `json:"level"`
+		Contact *string `json:"contact"`
+		Phone   *string `json:"phone"`
+		// Only the numbers sent are changed
+		Phones *struct {
+			Primary   *string `json:"primary"`
+			Extension *string `json:"extension"`
+			Alternate *string `json:"alternate"`
+		} `json:"phones"`
+		Email   *string    `json:"email"`
+		Website *string    `json:"website"`
+		Address *string    `json:"address"`
+		Mode    data.Modes `json:"mode"`
+		// Languages taught
+		Languages data.Languages `json:"languages"`
+		// The viewer visibility setting
+		PublicContact *bool `json:"public_contact"`
+		// Capacity data
+		StudentCapacity   *int32 `json:"student_capacity"`
+		CurrentEnrollment *int32 `json:"current_enrollment"`
+		EnrollmentPrivate *bool  `json:"enrollment_private"`
 	}
 	// Initialize a new json.Decoder instance
-	err = app.readJSON(w, r, &input)
+	err := app.readJSON(w, r, &input)
 	if err != nil {
-		app.badRequestResponse(w, r, err)
-		return
+		return err
 	}
+	v.Check(input.Phone == nil || input.Phones == nil, "phones", "phone_conflict")
 	// Check for updates
 	if input.Name != nil {
 		forum.Name = *input.Name
@@ -146,8 +414,20 @@ func (app *application) updateForumHandler(w http.ResponseWriter, r *http.Reques
 		forum.Contact = *input.Contact
 	}
 	if input.Phone != nil {
-		forum.Phone = *input.Phone
+		forum.Phones.Primary = *input.Phone
+	}
+	if input.Phones != nil {
+		if input.Phones.Primary != nil {
+			forum.Phones.Primary = *input.Phones.Primary
+		}
+		if input.Phones.Extension != nil {
+			forum.Phones.Extension = *input.Phones.Extension
+		}
+		if input.Phones.Alternate != nil {
+			forum.Phones.Alternate = *input.Phones.Alternate
+		}
 	}
+	forum.Phones.Normalize()
 	if input.Email != nil {
 		forum.Email = *input.Email
 	}
@@ -160,45 +440,96 @@ func (app *application) updateForumHandler(w http.ResponseWriter, r *http.Reques
 	if input.Mode != nil {
 		forum.Mode = input.Mode
 	}
-	// Perform validation on the updated Forum. If validation fails, then
-	// we send a 422 - Unprocessable Entity response to the client
-	// Initialize a new Validator instance
-	v := validator.New()
+	if input.Languages != nil {
+		forum.Languages = input.Languages
+	}
+	if input.PublicContact != nil {
+		forum.PublicContact = *input.PublicContact
+	}
+	if input.StudentCapacity != nil {
+		forum.StudentCapacity = input.StudentCapacity
+	}
+	if input.CurrentEnrollment != nil {
+		forum.CurrentEnrollment = input.CurrentEnrollment
+	}
+	if input.EnrollmentPrivate != nil {
+		forum.EnrollmentPrivate = *input.EnrollmentPrivate
+	}
+	return nil
+}
 
-	// Check the map to determine if there were any validation errors
-	if data.ValidateForum(v, forum); !v.Valid() {
-		app.failedValidationResponse(w, r, v.Errors)
+// archiveForumHandler for the "POST /v1/forums/:id/archive" endpoint marks
+// a forum as closed for now. It stays readable by id but drops out of the
+// listings, search and feed until it is unarchived
+func (app *application) archiveForumHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readForumID(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
-	// Pass the updated Forum record to the Update() method
-	err = app.models.Forums.Update(forum)
+	var input struct {
+		Reason string `json:"reason"`
+	}
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	forum, err := app.models.Forums.GetPrimary(r.Context(), id)
 	if err != nil {
 		switch {
-		case errors.Is(err, data.ErrEditConflict):
-			app.editConflictResponse(w, r)
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
 		return
 	}
-	// Write the data returned by Update()
-	err = app.writeJSON(w, http.StatusOK, envelope{"forum": forum}, nil)
-	if err != nil {
-		app.serverErrorResponse(w, r, err)
+	if forum.Archived() {
+		app.errorResponse(w, r, http.StatusConflict, app.translator(r).T("forum_archived"))
+		return
 	}
+	forum.ArchivedAt = data.NullTime{Time: app.clock.Now()}
+	forum.ArchiveReason = strings.TrimSpace(input.Reason)
+	v := validator.New()
+	data.ValidateForum(v, forum)
+	app.moderate(v, forum)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+	app.saveArchiveState(w, r, forum)
 }
 
-func (app *application) deleteForumHandler(w http.ResponseWriter, r *http.Request) {
-	// Get the id for the forum that needs to be deleted
-	id, err := app.readIDParam(r)
+// unarchiveForumHandler for the "POST /v1/forums/:id/unarchive" endpoint
+// reopens an archived forum. The contact details may have changed while
+// it was closed, so they have to be sent again and pass validation
+// before the forum shows up in the listings again
+func (app *application) unarchiveForumHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readForumID(r)
 	if err != nil {
-		app.notFoundResponse(w, r)
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
-	// Delete the Forum from the database. Send a 404 Not Found status code to the
-	// client if there is no matching record
-	err = app.models.Forums.Delete(id)
-	// Handle errors
+	var input struct {
+		Contact *string      `json:"contact"`
+		Phones  *data.Phones `json:"phones"`
+		Email   *string      `json:"email"`
+	}
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	forum, err := app.models.Forums.GetPrimary(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -208,8 +539,49 @@ func (app *application) deleteForumHandler(w http.ResponseWriter, r *http.Reques
 		}
 		return
 	}
-	// Return 200 Status OK to the client with a successful message
-	err = app.writeJSON(w, http.StatusOK, envelope{"message": "forum successfully deleted"}, nil)
+	if !forum.Archived() {
+		app.errorResponse(w, r, http.StatusConflict, app.translator(r).T("forum_not_archived"))
+		return
+	}
+	v := validator.New()
+	v.Check(input.Contact != nil, "contact", "required")
+	v.Check(input.Phones != nil, "phones", "required")
+	v.Check(input.Email != nil, "email", "required")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+	forum.Contact = strings.TrimSpace(*input.Contact)
+	forum.Phones = *input.Phones
+	forum.Phones.Normalize()
+	forum.Email = strings.TrimSpace(*input.Email)
+	forum.ArchivedAt = data.NullTime{}
+	forum.ArchiveReason = ""
+	data.ValidateForum(v, forum)
+	app.moderate(v, forum)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+	app.saveArchiveState(w, r, forum)
+}
+
+// The saveArchiveState() method writes an archived or reopened forum and
+// sends it back
+func (app *application) saveArchiveState(w http.ResponseWriter, r *http.Request, forum *data.Forum) {
+	err := app.models.Forums.Update(r.Context(), forum)
+	app.cache.Invalidate(forum.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	app.redactForums(r, forum)
+	err = app.writeResource(w, r, http.StatusOK, "forum", forum, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -218,43 +590,167 @@ func (app *application) deleteForumHandler(w http.ResponseWriter, r *http.Reques
 // The listForumsHandler allows the client to see a listing of forums
 // based on a set of criteria
 func (app *application) listForumsHandler(w http.ResponseWriter, r *http.Request) {
-	// Create an input struct to hold our query parameters
-	var input struct {
-		Name  string
-		Level string
-		Mode  []string
-		data.Filters
-	}
 	// Initialize a validator
 	v := validator.New()
+	input := app.readListInput(r, v)
+	// Check for validation errors
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+	// Get a listing of all forums
+	forums, metadata, err := app.models.Forums.GetAll(r.Context(), input.ForumFilter, input.Filters)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrQueryTimeout):
+			app.queryTimeoutResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if len(input.Facets) > 0 {
+		metadata.Facets, err = app.models.Forums.Facets(r.Context(), input.ForumFilter, input.Facets)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	// Listings only carry the summary, the full forum is one request away.
+	// Summaries have no flat phone, so they skip redactForums()
+	summaries := make([]data.ForumSummary, len(forums))
+	for i, forum := range forums {
+		forum.Redact(app.viewer(r, forum))
+		summaries[i] = forum.Summary()
+	}
+	// Send a JSON response containing all the forums
+	err = app.writeCollection(w, r, http.StatusOK, "forums", summaries, metadata, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+// A listInput holds the query parameters of the forum listing
+type listInput struct {
+	Facets []string
+	data.ForumFilter
+	data.Filters
+}
+
+// The readListInput() method reads and checks the query parameters of
+// the forum listing. Problems are recorded in v
+func (app *application) readListInput(r *http.Request, v *validator.Validator) listInput {
+	var input listInput
 	// Get the URL values map
 	qs := r.URL.Query()
 	// Use the helper methods to extract the values
-	input.Name = app.readString(qs, "name", "")
+	input.Name = strings.TrimSpace(app.readString(qs, "name", ""))
+	// Guard the full-text search against pathological terms
+	v.Check(len(input.Name) <= 100, "name", "max_bytes", 100)
 	input.Level = app.readString(qs, "level", "")
-	input.Mode = app.readCSV(qs, "mode", []string{})
+	input.Mode = app.readCSV(qs, "mode", []string{}, v)
+	// Forums teaching in every listed language
+	input.Languages = app.readCSV(qs, "languages", []string{}, v)
+	v.Check(validator.EachIn(input.Languages, data.LanguageList...), "languages", "invalid_language", strings.Join(data.LanguageList, ", "))
+	// Limit to forums created within a period
+	input.Created.After = app.readTime(qs, "created_after", time.Time{}, v)
+	input.Created.Before = app.readTime(qs, "created_before", time.Time{}, v)
+	if !input.Created.After.IsZero() && !input.Created.Before.IsZero() {
+		v.Check(input.Created.Before.After(input.Created.After), "created_before", "after_created_after")
+	}
+	input.Available = app.readBool(qs, "has_availability", false, v)
+	input.Archived = app.readBool(qs, "archived", false, v)
+	// Expired listings are hidden from everyone but admins
+	input.Expired = app.readBool(qs, "expired", false, v)
+	v.Check(!input.Expired || app.privileged(r), "expired", "admin_only")
+	// Looking a forum up by phone number is admin tooling too
+	if phone := app.readString(qs, "phone", ""); phone != "" {
+		input.Phone = data.PhoneDigits(phone)
+		v.Check(input.Phone != "", "phone", "invalid_phone")
+		v.Check(app.privileged(r), "phone", "admin_only")
+	}
+	// Facet counts are only worked out when asked for
+	input.Facets = app.readCSV(qs, "facets", []string{}, v)
+	v.Check(validator.EachIn(input.Facets, data.FacetDimensions...), "facets", "invalid_facet", strings.Join(data.FacetDimensions, ", "))
+	v.Check(validator.Unique(input.Facets), "facets", "duplicate_entries")
 	// Get the page information
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
-	// Get the sort information
-	input.Filters.Sort = app.readString(qs, "sort", "id")
+	// Get the sort information, a comma-separated list of sort keys
+	input.Filters.Sort = app.readCSV(qs, "sort", []string{"id"}, v)
 	// Specify the allowed sort values
 	input.Filters.SortList = []string{"id", "name", "level", "-id", "-name", "-level"}
-	// Check for validation errors
-	if data.ValidateFilers(v, input.Filters); !v.Valid() {
-		app.failedValidationResponse(w, r, v.Errors)
-		return
+	if app.config.features.views {
+		input.Filters.SortList = append(input.Filters.SortList, "-trending")
 	}
-	// Get a listing of all forums
-	forums, metadata, err := app.models.Forums.GetAll(input.Name, input.Level, input.Mode, input.Filters)
+	input.Filters.MaxOffset = app.config.list.maxOffset
+	data.ValidateFilers(v, input.Filters)
+	return input
+}
+
+// showForumSchemaHandler for the "GET /v1/forums/schema" endpoint returns
+// the validation rules for the create/update forum input
+func (app *application) showForumSchemaHandler(w http.ResponseWriter, r *http.Request) {
+	err := app.writeResource(w, r, http.StatusOK, "schema", data.ForumSchema(), nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
-		return
 	}
-	// Send a JSON response containing all the forums
-	err = app.writeJSON(w, http.StatusOK, envelope{"forums": forums, "metadata": metadata}, nil)
-	if err != nil {
-		app.serverErrorResponse(w, r, err)
+}
+
+// The redactForums() method hides the fields the client is not allowed to
+// see. Every response that carries a forum goes through it, including the
+// ones sent back after a write, so they can't diverge. The full forum
+// still carries the flat phone, so the deprecation is noted here too
+func (app *application) redactForums(r *http.Request, forums ...*data.Forum) {
+	for _, forum := range forums {
+		forum.Redact(app.viewer(r, forum))
+	}
+	// X-Strict only refuses deprecated input, so the error is of no use
+	_ = app.deprecated(r, "phone_flat_output")
+}
+
+// The viewer() method works out how the client relates to a forum.
+// Requests don't carry an authenticated user yet, so only a privileged
+// client sees more than the public does
+func (app *application) viewer(r *http.Request, forum *data.Forum) data.Viewer {
+	if app.privileged(r) {
+		return data.AdminViewer
+	}
+	return data.PublicViewer
+}
+
+// The privileged() method reports whether the client is an admin. There
+// are no accounts yet, so unless an admin check is plugged into
+// app.admin no client is
+func (app *application) privileged(r *http.Request) bool {
+	return app.admin != nil && app.admin(r)
+}
+
+// The moderate() method runs the content filter over a forum. Fields with
+// clear violations fail validation. Borderline forums are still accepted
+// since there is no review queue yet, but they are logged for a person to
+// look at
+func (app *application) moderate(v *validator.Validator, forum *data.Forum) {
+	app.applyModeration(v, app.moderation.Score(forum), fmt.Sprintf("forum %q", forum.Name))
+}
+
+// The moderateFAQ() method runs the content filter over the question and
+// answer of a FAQ, the same way as moderate()
+func (app *application) moderateFAQ(v *validator.Validator, faq *data.FAQ) {
+	app.applyModeration(v, app.moderation.ScoreFAQ(faq), fmt.Sprintf("faq of forum %d", faq.ForumID))
+}
+
+// The applyModeration() method fails the rejected fields of a result or
+// logs a flagged one, naming it by what
+func (app *application) applyModeration(v *validator.Validator, result moderation.Result, what string) {
+	if result.Rejected() {
+		for field, score := range result.Fields {
+			v.Check(score < moderation.Reject, field, "moderation_rejected")
+		}
 		return
 	}
+	if result.Flagged() {
+		app.logger.Printf("%s flagged for moderation review (score %d)", what, result.Score)
+	}
 }
@@ -0,0 +1,303 @@
+// Filename: cmd/api/forum_test.go
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"AWD_FinalProject.ryanarmstrong.net/internal/data"
+	"AWD_FinalProject.ryanarmstrong.net/internal/validator"
+)
+
+// An id that was just found missing is answered from memory, without a
+// query, until the miss is forgotten
+func TestShowForumRemembersMisses(t *testing.T) {
+	app := newTestApplication(t)
+	app.publicIDs.Set("mmmmmmmmmm", 8, app.publicIDs.Stamp())
+	app.misses.Set(8, struct{}{}, app.misses.Stamp())
+	srv := app.routes()
+
+	before := metricValue("negative_cache_hits")
+	rr := httptest.NewRecorder()
+	srv.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/forums/mmmmmmmmmm", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("got status %d; want %d", rr.Code, http.StatusNotFound)
+	}
+	if got := metricValue("negative_cache_hits") - before; got != 1 {
+		t.Errorf("negative_cache_hits went up by %d; want 1", got)
+	}
+}
+
+// A public id that was just found not to exist is answered from memory
+// too, before any lookup. The models have no database here, so a query
+// would end in a 500
+func TestShowForumRemembersPublicMisses(t *testing.T) {
+	app := newTestApplication(t)
+	app.publicMisses.Set("nnnnnnnnnn", struct{}{}, app.publicMisses.Stamp())
+	srv := app.routes()
+
+	before := metricValue("negative_cache_hits")
+	for _, path := range []string{"/v1/forums/nnnnnnnnnn", "/v1/forums/nnnnnnnnnn/faqs/1"} {
+		method := http.MethodGet
+		if path != "/v1/forums/nnnnnnnnnn" {
+			method = http.MethodDelete
+		}
+		rr := send(t, srv, method, path, "")
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("%s %s: got status %d; want %d", method, path, rr.Code, http.StatusNotFound)
+		}
+	}
+	if got := metricValue("negative_cache_hits") - before; got != 2 {
+		t.Errorf("negative_cache_hits went up by %d; want 2", got)
+	}
+}
+
+// A forum served from the cache is redacted like any other. The public
+// never sees the serial id, nor contact details the forum keeps private,
+// and the copy in the cache keeps them
+func TestShowForumRedacted(t *testing.T) {
+	app := newTestApplication(t)
+	app.publicIDs.Set("pppppppppp", 8, app.publicIDs.Stamp())
+	app.publicIDs.Set("qqqqqqqqqq", 9, app.publicIDs.Stamp())
+	app.cache.Set(8, newTestForum(withID(8, "pppppppppp"), withEnrollment(12, true)), app.cache.Stamp())
+	app.cache.Set(9, newTestForum(withID(9, "qqqqqqqqqq"), withName("Open Group"), withPublicContact()), app.cache.Stamp())
+	srv := app.routes()
+
+	show := func(publicID string) map[string]interface{} {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		srv.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/forums/"+publicID, nil))
+		if rr.Code != http.StatusOK || rr.Header().Get("X-Cache") != "HIT" {
+			t.Fatalf("got status %d, X-Cache %q; want a cached 200", rr.Code, rr.Header().Get("X-Cache"))
+		}
+		var body struct {
+			Forum map[string]interface{} `json:"forum"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		return body.Forum
+	}
+
+	private := show("pppppppppp")
+	for _, key := range []string{"id", "email", "phones", "phone", "current_enrollment"} {
+		if value, ok := private[key]; ok && value != "" {
+			t.Errorf("got %s %v for the public; want it hidden", key, value)
+		}
+	}
+	if private["public_id"] != "pppppppppp" {
+		t.Errorf("got public_id %v; want pppppppppp", private["public_id"])
+	}
+	if cached, _ := app.cache.Get(8); cached.Email == "" || cached.ID != 8 {
+		t.Error("redacting the response changed the cached forum")
+	}
+
+	open := show("qqqqqqqqqq")
+	if _, ok := open["id"]; ok {
+		t.Errorf("got id %v for the public; want it hidden", open["id"])
+	}
+	if open["email"] != "study@example.com" || open["phone"] == nil || open["phones"] == nil {
+		t.Errorf("got %v; want the public contact details with the deprecated phone", open)
+	}
+}
+
+// The metricValue() function reads one of our integer counters, zero if
+// it hasn't been touched yet
+func metricValue(name string) int64 {
+	if v, ok := metrics.Get(name).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
+
+// A body that can't be decoded is a 400. One that decodes but breaks the
+// forum rules is a 422 naming the fields. Neither gets as far as the
+// models
+func TestCreateForumBodyErrors(t *testing.T) {
+	srv := newTestApplication(t).routes()
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		fields []string
+	}{
+		{"bad syntax", `{"name": }`, http.StatusBadRequest, nil},
+		{"wrong type", `{"name": 12}`, http.StatusBadRequest, nil},
+		{"unknown key", `{"colour": "red"}`, http.StatusBadRequest, nil},
+		{"two values", `{} {}`, http.StatusBadRequest, nil},
+		{"empty object", `{}`, http.StatusUnprocessableEntity, []string{"name", "level", "contact", "phones.primary", "email", "website", "address", "mode"}},
+		{"bad email", `{"name": "a", "level": "b", "contact": "c", "phone": "501-223-4455", "email": "nobody", "website": "https://example.com", "address": "d", "mode": ["online"]}`, http.StatusUnprocessableEntity, []string{"email"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := send(t, srv, http.MethodPost, "/v1/forums", tt.body)
+			if rr.Code != tt.status {
+				t.Fatalf("got status %d; want %d", rr.Code, tt.status)
+			}
+			if tt.fields == nil {
+				return
+			}
+			got := errorFields(t, rr)
+			if len(got) != len(tt.fields) {
+				t.Errorf("got errors %v; want errors for %v", got, tt.fields)
+			}
+			for _, field := range tt.fields {
+				if _, ok := got[field]; !ok {
+					t.Errorf("no error for %s in %v", field, got)
+				}
+			}
+		})
+	}
+}
+
+// Bad query parameters on the listing are field errors too
+func TestListForumsQueryErrors(t *testing.T) {
+	srv := newTestApplication(t).routes()
+
+	tests := []struct {
+		query string
+		field string
+	}{
+		{"mode=online,,evening", "mode"},
+		{"languages=en,fr", "languages"},
+		{"page=abc", "page"},
+		{"page=99999999999999999999", "page"},
+		{"page_size=500", "page_size"},
+		{"sort=colour", "sort"},
+		{"sort=name,-name", "sort"},
+		{"has_availability=maybe", "has_availability"},
+		{"created_after=yesterday", "created_after"},
+		{"created_after=2026-02-01&created_before=2026-01-01", "created_before"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rr := send(t, srv, http.MethodGet, "/v1/forums?"+tt.query, "")
+			if rr.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("got status %d; want %d", rr.Code, http.StatusUnprocessableEntity)
+			}
+			if _, ok := errorFields(t, rr)[tt.field]; !ok {
+				t.Errorf("no error for %s in %s", tt.field, rr.Body.String())
+			}
+		})
+	}
+}
+
+// The readListing() function reads the listing parameters of query as the
+// client, an admin when admin is set, would send them
+func readListing(t *testing.T, query string, admin bool) (listInput, map[string]string) {
+	t.Helper()
+	app := newTestApplication(t)
+	app.admin = func(r *http.Request) bool { return admin }
+	v := validator.New()
+	input := app.readListInput(httptest.NewRequest(http.MethodGet, "/v1/forums?"+query, nil), v)
+	return input, v.Errors
+}
+
+// Only admins may list the expired forums. Everyone else is told so
+// rather than quietly getting the current ones
+func TestListForumsExpired(t *testing.T) {
+	srv := newTestApplication(t).routes()
+	rr := send(t, srv, http.MethodGet, "/v1/forums?expired=true", "")
+	if rr.Code != http.StatusUnprocessableEntity || errorFields(t, rr)["expired"] != "is only available to admins" {
+		t.Errorf("got %d %s for the public; want expired refused", rr.Code, rr.Body.String())
+	}
+
+	input, errs := readListing(t, "expired=true", true)
+	if len(errs) != 0 || !input.Expired {
+		t.Errorf("got errors %v, expired %t for an admin; want the filter set", errs, input.Expired)
+	}
+	if input, _ := readListing(t, "", true); input.Expired {
+		t.Error("got the expired filter without asking for it")
+	}
+}
+
+// Looking a forum up by phone is admin tooling. An admin's search is kept
+// as the bare digits the data layer compares
+func TestListForumsPhone(t *testing.T) {
+	if _, errs := readListing(t, "phone=501-223-4455", false); errs["phone"] != "admin_only" {
+		t.Errorf("got errors %v for the public; want phone refused", errs)
+	}
+	input, errs := readListing(t, "phone=(501)+223.4455", true)
+	if len(errs) != 0 || input.Phone != "5012234455" {
+		t.Errorf("got errors %v, phone %q for an admin; want 5012234455", errs, input.Phone)
+	}
+	if _, errs := readListing(t, "phone=none", true); errs["phone"] != "invalid_phone" {
+		t.Errorf("got errors %v for a number without digits; want invalid_phone", errs)
+	}
+}
+
+// A sort list in the query string keeps its order, and the listing
+// always settles ties on the id
+func TestListForumsSortTies(t *testing.T) {
+	input, errs := readListing(t, "sort=level,-name", false)
+	if len(errs) != 0 || !slices.Equal(input.Sort, []string{"level", "-name"}) {
+		t.Fatalf("got errors %v, sort %v; want level,-name", errs, input.Sort)
+	}
+	if _, errs := readListing(t, "sort=name,-name", false); errs["sort"] != "contradictory_sort" {
+		t.Errorf("got errors %v for name and -name; want contradictory_sort", errs)
+	}
+}
+
+// Once an update has invalidated a forum the next read can't be the copy
+// from before it, even when the update lands while that copy is being read
+func TestCachedForumNoStaleRead(t *testing.T) {
+	app := newTestApplication(t)
+	row := newTestForum(withID(7, "ssssssssss"))
+	reads := 0
+	// update changes the row and invalidates it, as the update handlers do
+	update := func(name string) {
+		row.Name = name
+		row.Version++
+		app.cache.Invalidate(row.ID)
+	}
+	var during func()
+	fetch := func(ctx context.Context, id int64) (*data.Forum, error) {
+		reads++
+		forum := row
+		if during != nil {
+			during()
+			during = nil
+		}
+		return &forum, nil
+	}
+	read := func(wantVersion int32, wantHit bool) {
+		t.Helper()
+		forum, hit, err := app.cachedForum(context.Background(), row.ID, fetch)
+		if err != nil || forum.Version != wantVersion || hit != wantHit {
+			t.Fatalf("got version %d, hit %t, %v; want version %d, hit %t", forum.Version, hit, err, wantVersion, wantHit)
+		}
+	}
+
+	read(1, false)
+	read(1, true)
+	update("Study Hall")
+	// A second update lands after the row was read but before it is
+	// cached. That read is answered, but the copy isn't kept
+	during = func() { update("Reading Room") }
+	read(2, false)
+	read(3, false)
+	read(3, true)
+	if reads != 3 {
+		t.Errorf("got %d reads; want 3", reads)
+	}
+}
+
+// The forums tests build start out valid, whatever options they take
+func TestNewTestForum(t *testing.T) {
+	forum := newTestForum(withID(3, "cccccccccc"), withName("Open Group"), withPublicContact(), withEnrollment(12, true))
+	v := validator.New()
+	data.ValidateForum(v, &forum)
+	if !v.Valid() {
+		t.Errorf("got errors %v; want a valid forum", v.Errors)
+	}
+	if forum.ID != 3 || forum.Name != "Open Group" || !forum.PublicContact || *forum.CurrentEnrollment != 12 {
+		t.Errorf("got %+v; want the options applied", forum)
+	}
+}
@@ -3,21 +3,55 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"net/http"
+	"time"
 )
 
 func (app *application) healthcheckHandler(w http.ResponseWriter, r *http.Request) {
+	// Report each connection pool on its own so a replica outage is visible
+	database := map[string]string{
+		"primary": pingStatus(r.Context(), app.models.Forums.DB),
+	}
+	if app.models.Forums.ReadDB != nil {
+		database["replica"] = pingStatus(r.Context(), app.models.Forums.ReadDB)
+	}
+	// Without the primary nothing but this endpoint works
+	status, code := "available", http.StatusOK
+	if database["primary"] != "available" {
+		status, code = "unavailable", http.StatusServiceUnavailable
+	}
 	// Create a map to hold our healthcheck data
 	data := envelope{
-		"status": "available",
+		"status": status,
 		"system_info": map[string]string{
 			"environment": app.config.env,
-			"version":     version,
+			"version":     build.Version,
 		},
+		"database": database,
 	}
-	err := app.writeJSON(w, http.StatusOK, data, nil)
+	err := app.writeJSON(w, r, code, data, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 }
+
+// The pingStatus() function reports whether a pool answers a ping. It
+// sorts failures with errorKind() like every other handler, and a ping
+// that runs out of time also counts as the database being unavailable
+func pingStatus(ctx context.Context, db *sql.DB) string {
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	err := db.PingContext(ctx)
+	switch {
+	case err == nil:
+		return "available"
+	case errorKind(err) == kindUnavailable, errors.Is(err, context.DeadlineExceeded):
+		return "unavailable"
+	default:
+		return "error"
+	}
+}
@@ -3,6 +3,7 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -11,7 +12,9 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
+	"AWD_FinalProject.ryanarmstrong.net/internal/data"
 	"AWD_FinalProject.ryanarmstrong.net/internal/validator"
 	"github.com/julienschmidt/httprouter"
 )
@@ -19,20 +22,98 @@ import (
 // Define a new type named envelope
 type envelope map[string]interface{}
 
-func (app *application) readIDParam(r *http.Request) (int64, error) {
+// The readInt64Param() method returns the named route parameter as a
+// positive integer. Handlers answer with a 404 when it is malformed
+func (app *application) readInt64Param(r *http.Request, name string) (int64, error) {
 	// Use the "ParamsFromContext()" function to get the request context as a slice
 	params := httprouter.ParamsFromContext(r.Context())
-	// Get the value of the "id" parameter
-	id, err := strconv.ParseInt(params.ByName("id"), 10, 64)
+	// Get the value of the named parameter
+	id, err := strconv.ParseInt(params.ByName(name), 10, 64)
 	if err != nil || id < 1 {
-		return 0, errors.New("Invalid id parameter")
+		return 0, fmt.Errorf("invalid %s parameter", name)
 	}
 	return id, nil
 }
 
-func (app *application) writeJSON(w http.ResponseWriter, status int, data envelope, headers http.Header) error {
-	// Convert our map into a JSON object
-	js, err := json.MarshalIndent(data, "", "\t")
+// The readForumID() method returns the serial id of the forum named by
+// the "id" route parameter, which may hold either the public id or the
+// serial id. Only privileged clients may use the serial one, for everyone
+// else it is answered like an id that doesn't exist so the serial ids
+// can't be walked. An id that doesn't resolve is data.ErrRecordNotFound
+func (app *application) readForumID(r *http.Request) (int64, error) {
+	params := httprouter.ParamsFromContext(r.Context())
+	value := params.ByName("id")
+	if data.PublicIDRX.MatchString(value) {
+		return app.resolvePublicID(r, value)
+	}
+	id, err := app.readInt64Param(r, "id")
+	if err != nil || !app.privileged(r) {
+		return 0, data.ErrRecordNotFound
+	}
+	return id, nil
+}
+
+// The resolvePublicID() method turns a public id into the serial id.
+// Public ids never change, so the answers are kept in memory. So are the
+// ones that don't exist, for missTTL, like serial ids in app.misses
+func (app *application) resolvePublicID(r *http.Request, publicID string) (int64, error) {
+	if id, ok := app.publicIDs.Get(publicID); ok {
+		return id, nil
+	}
+	if _, missed := app.publicMisses.Get(publicID); missed {
+		metrics.Add("negative_cache_hits", 1)
+		return 0, data.ErrRecordNotFound
+	}
+	stamp := app.publicIDs.Stamp()
+	missStamp := app.publicMisses.Stamp()
+	id, err := app.models.Forums.ResolvePublicID(r.Context(), publicID)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			app.publicMisses.Set(publicID, struct{}{}, missStamp)
+		}
+		return 0, err
+	}
+	app.publicIDs.Set(publicID, id, stamp)
+	return id, nil
+}
+
+// The Content-Type of every JSON response we send
+const jsonContentType = "application/json; charset=utf-8"
+
+// The writeJSON() method sends data as the JSON response body. Output is
+// compact unless the client asks for ?pretty=true or sends X-Pretty: true.
+// Deprecated features the request used are listed under "warnings"
+func (app *application) writeJSON(w http.ResponseWriter, r *http.Request, status int, data interface{}, headers http.Header) error {
+	// Don't bother encoding a response for a client that has gone away
+	if r.Context().Err() != nil {
+		metrics.Add("aborted_requests", 1)
+		return nil
+	}
+	// A handler that carries on after sending a response must not add a
+	// second body to it
+	if responseStarted(w) {
+		metrics.Add("duplicate_writes", 1)
+		app.logger.Printf("response already sent for %s %s, dropping a second write", r.Method, r.URL.Path)
+		return nil
+	}
+	// Bare payloads only get the headers
+	if warnings := app.deprecationWarnings(w, r); warnings != nil {
+		if env, ok := data.(envelope); ok {
+			withWarnings := envelope{"warnings": warnings}
+			for key, value := range env {
+				withWarnings[key] = value
+			}
+			data = withWarnings
+		}
+	}
+	// Convert our data into a JSON object
+	var js []byte
+	var err error
+	if wantsPretty(r) {
+		js, err = json.MarshalIndent(data, "", "\t")
+	} else {
+		js, err = json.Marshal(data)
+	}
 	if err != nil {
 		return err
 	}
@@ -43,27 +124,64 @@ func (app *application) writeJSON(w http.ResponseWriter, status int, data envelo
 		w.Header()[key] = value
 	}
 	// Specify that we will serve our responses using JSON
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
+	w.Header().Set("Content-Type", jsonContentType)
 	// Write the []byte slice containing the JSON response body
-	w.Write(js)
+	writeBody(w, r, status, js)
 	return nil
 }
 
+// The urlFor() method builds an absolute link to one of our resources
+// from -base-url and a path format such as "/v1/forums/%d"
+func (app *application) urlFor(format string, args ...interface{}) string {
+	return strings.TrimSuffix(app.config.baseURL, "/") + fmt.Sprintf(format, args...)
+}
+
+// The wantsPretty() function reports whether the client asked for
+// indented JSON. Values that aren't booleans are ignored
+func wantsPretty(r *http.Request) bool {
+	value := r.URL.Query().Get("pretty")
+	if value == "" {
+		value = r.Header.Get("X-Pretty")
+	}
+	pretty, _ := strconv.ParseBool(value)
+	return pretty
+}
+
 func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst interface{}) error {
 	// Use http.MaxBytesReader() to limit the size of the request body to
 	// 1 MB 2^20
 	maxBytes := 1_048_576
-	// Decode the request body into the target destination
+	// Read the whole (size-limited) body once so we can check its shape
+	// before decoding it
 	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes))
-	dec := json.NewDecoder(r.Body)
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			return fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit)
+		}
+		return err
+	}
+	if !simpleJSON(body) {
+		return errors.New("body is too complex")
+	}
+	if err := app.checkDeprecatedInput(r, body); err != nil {
+		return err
+	}
+	body, err = app.rejectReadOnly(r, body)
+	if err != nil {
+		return err
+	}
+	// Decode the request body into the target destination
+	dec := json.NewDecoder(bytes.NewReader(body))
 	dec.DisallowUnknownFields()
-	err := dec.Decode(dst)
+	err = dec.Decode(dst)
 	// Check for a bad request
 	if err != nil {
 		var syntaxError *json.SyntaxError
 		var unmarshalTypeError *json.UnmarshalTypeError
 		var invalidUnmarshalError *json.InvalidUnmarshalError
+		var tooManyEntriesError *data.TooManyEntriesError
 
 		// Switch to check for the errors
 		switch {
@@ -78,17 +196,16 @@ func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst int
 				return fmt.Errorf("body contains incorrect JSON type for field %q", unmarshalTypeError.Field)
 			}
 			return fmt.Errorf("body contains incorrect JSON type (at character %d)", unmarshalTypeError.Offset)
+		// An array far past any sensible size
+		case errors.As(err, &tooManyEntriesError):
+			return fmt.Errorf("body contains more than %d entries for field %q", tooManyEntriesError.Limit, tooManyEntriesError.Field)
 		// Empty body
 		case errors.Is(err, io.EOF):
 			return errors.New("body must not be empty")
 
-		// Unmappable fields
-		case strings.HasPrefix(err.Error(), "json: unkown field "):
-			fieldName := strings.TrimPrefix(err.Error(), "json: unkown field")
-			return fmt.Errorf("body contains unknown key %s", fieldName)
-		// Too large
-		case err.Error() == "http: request body too large":
-			return fmt.Errorf("body must not be large than %d bytes", maxBytes)
+		// Unmappable fields, with a suggestion when it looks like a typo
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			return unknownFieldError(err.Error(), dst)
 
 		// Pass non-nil pointer error
 		case errors.As(err, &invalidUnmarshalError):
@@ -100,12 +217,46 @@ func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst int
 	}
 	// Call Decode() again
 	err = dec.Decode(&struct{}{})
-	if err != io.EOF {
+	if !errors.Is(err, io.EOF) {
 		return errors.New("body must only contain a single JSON value")
 	}
 	return nil
 }
 
+// Limits on the shape of a request body. A forum body is a single flat
+// object, so these are far beyond anything a real client sends
+const (
+	maxJSONDepth  = 20
+	maxJSONTokens = 10_000
+)
+
+// The simpleJSON() function walks the tokens of a body and reports whether
+// it stays within maxJSONDepth and maxJSONTokens. A deeply nested body can
+// eat CPU in the decoder even under the size cap. Syntax errors are left
+// for the real decode to report
+func simpleJSON(body []byte) bool {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	depth := 0
+	for tokens := 0; ; tokens++ {
+		if tokens > maxJSONTokens {
+			return false
+		}
+		token, err := dec.Token()
+		if err != nil {
+			return true
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+			if depth > maxJSONDepth {
+				return false
+			}
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+}
+
 // The readString() method returns a string value from the query parameter
 // string or returns a default value if no matching key is found
 func (app *application) readString(qs url.Values, key string, defaultValue string) string {
@@ -119,14 +270,22 @@ func (app *application) readString(qs url.Values, key string, defaultValue strin
 
 // The readCSV() method splits a value into a slice based on the comma separater
 // If no matching key is found then the default value is returned
-func (app *application) readCSV(qs url.Values, key string, defaultValue []string) []string {
+// Empty entries (e.g "a,,b") are recorded in the validation errors map
+func (app *application) readCSV(qs url.Values, key string, defaultValue []string, v *validator.Validator) []string {
 	// Get the value
 	value := qs.Get(key)
 	if value == "" {
 		return defaultValue
 	}
 	// Split the string based on the "," delimiter
-	return strings.Split(value, ",")
+	values := strings.Split(value, ",")
+	for i := range values {
+		values[i] = strings.TrimSpace(values[i])
+		if values[i] == "" {
+			v.AddError(key, "empty_entry")
+		}
+	}
+	return values
 }
 
 // The readInt() method converts a string value from the query string to an integer value
@@ -134,15 +293,54 @@ func (app *application) readCSV(qs url.Values, key string, defaultValue []string
 // the validation errors map
 func (app *application) readInt(qs url.Values, key string, defaultValue int, v *validator.Validator) int {
 	// Get the value
-	value := qs.Get(key)
+	value := strings.TrimSpace(qs.Get(key))
 	if value == "" {
 		return defaultValue
 	}
 	// Perform the conversion to an integer
 	intValue, err := strconv.Atoi(value)
 	if err != nil {
-		v.AddError(key, "must be an integer value")
+		if errors.Is(err, strconv.ErrRange) {
+			v.AddError(key, "integer_range")
+		} else {
+			v.AddError(key, "integer")
+		}
 		return defaultValue
 	}
 	return intValue
 }
+
+// The readBool() method converts a value from the query string to a bool.
+// It accepts the forms strconv.ParseBool() does (true, false, 1, 0, ...)
+// and records a validation error for anything else
+func (app *application) readBool(qs url.Values, key string, defaultValue bool, v *validator.Validator) bool {
+	value := strings.TrimSpace(qs.Get(key))
+	if value == "" {
+		return defaultValue
+	}
+	boolValue, err := strconv.ParseBool(value)
+	if err != nil {
+		v.AddError(key, "boolean")
+		return defaultValue
+	}
+	return boolValue
+}
+
+// The readTime() method converts an RFC 3339 value (2024-01-15T10:30:00Z)
+// or a plain date (2024-01-15, taken as midnight UTC) from the query string
+// to a time.Time and records a validation error if it can't be parsed
+func (app *application) readTime(qs url.Values, key string, defaultValue time.Time, v *validator.Validator) time.Time {
+	value := strings.TrimSpace(qs.Get(key))
+	if value == "" {
+		return defaultValue
+	}
+	timeValue, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		timeValue, err = time.Parse(time.DateOnly, value)
+	}
+	if err != nil {
+		v.AddError(key, "rfc3339")
+		return defaultValue
+	}
+	return timeValue.UTC()
+}
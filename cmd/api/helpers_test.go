@@ -0,0 +1,157 @@
+// Filename: cmd/api/helpers_test.go
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"AWD_FinalProject.ryanarmstrong.net/internal/data"
+	"AWD_FinalProject.ryanarmstrong.net/internal/validator"
+)
+
+// The body readJSON() decodes into in these tests
+type testInput struct {
+	Name string     `json:"name"`
+	Mode data.Modes `json:"mode"`
+}
+
+// Each way a body can be malformed gets its own message
+func TestReadJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"valid", `{"name": "Study Group", "mode": ["online"]}`, ""},
+		{"empty", ``, "body must not be empty"},
+		{"bad syntax", `{"name": "Study Group",}`, "body contains badly-formed JSON(at character 24)"},
+		{"cut short", `{"name": "Study`, "body contains badly-formed JSON"},
+		{"wrong type", `{"name": 12}`, `body contains incorrect JSON type for field "name"`},
+		{"wrong type at the top", `["name"]`, "body contains incorrect JSON type (at character 1)"},
+		{"unknown key", `{"nmae": "Study Group"}`, `body contains unknown key "nmae", did you mean "name"?`},
+		{"two values", `{"name": "a"} {"name": "b"}`, "body must only contain a single JSON value"},
+		{"too large", `{"name": "` + strings.Repeat("a", 1_048_576) + `"}`, "body must not be larger than 1048576 bytes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			var input testInput
+			err := app.readJSON(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body)), &input)
+			got := ""
+			if err != nil {
+				got = err.Error()
+			}
+			if got != tt.want {
+				t.Errorf("got error %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSimpleJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want bool
+	}{
+		{"flat object", `{"name": "Study Group", "mode": ["online", "evening"]}`, true},
+		{"deepest allowed", strings.Repeat("[", maxJSONDepth) + strings.Repeat("]", maxJSONDepth), true},
+		{"one level too deep", strings.Repeat("[", maxJSONDepth+1) + strings.Repeat("]", maxJSONDepth+1), false},
+		{"deep objects", strings.Repeat(`{"a":`, maxJSONDepth+1) + "1" + strings.Repeat("}", maxJSONDepth+1), false},
+		// Depth is how far in a value is, not how many arrays there are
+		{"many shallow arrays", "[" + strings.Repeat("[],", 100) + "[]]", true},
+		{"too many tokens", "[" + strings.Repeat("1,", maxJSONTokens) + "1]", false},
+		// Broken bodies are left for the decoder to describe
+		{"bad syntax", `{"name": }`, true},
+		{"empty", ``, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := simpleJSON([]byte(tt.body)); got != tt.want {
+				t.Errorf("got %t; want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+// A body refused for its shape gets a 400 before it is decoded
+func TestReadJSONTooComplex(t *testing.T) {
+	app := newTestApplication(t)
+	body := `{"name": ` + strings.Repeat("[", 50) + strings.Repeat("]", 50) + `}`
+	var input testInput
+	err := app.readJSON(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)), &input)
+	if err == nil || err.Error() != "body is too complex" {
+		t.Errorf("got error %v; want \"body is too complex\"", err)
+	}
+}
+
+func TestReadQueryValues(t *testing.T) {
+	app := newTestApplication(t)
+	qs := url.Values{
+		"page":      {" 3 "},
+		"huge":      {"99999999999999999999"},
+		"word":      {"three"},
+		"on":        {"true"},
+		"one":       {"1"},
+		"maybe":     {"maybe"},
+		"stamp":     {"2026-01-15T10:30:00-06:00"},
+		"day":       {"2026-01-15"},
+		"yesterday": {"yesterday"},
+		"modes":     {"online, evening"},
+		"gaps":      {"online,,evening"},
+	}
+	v := validator.New()
+	if got := app.readInt(qs, "page", 1, v); got != 3 {
+		t.Errorf("readInt(page) = %d; want 3", got)
+	}
+	if got := app.readInt(qs, "missing", 1, v); got != 1 {
+		t.Errorf("readInt(missing) = %d; want the default", got)
+	}
+	if got := app.readBool(qs, "on", false, v); !got {
+		t.Error("readBool(on) = false; want true")
+	}
+	if got := app.readBool(qs, "one", false, v); !got {
+		t.Error("readBool(one) = false; want true")
+	}
+	if got, want := app.readTime(qs, "stamp", time.Time{}, v), time.Date(2026, 1, 15, 16, 30, 0, 0, time.UTC); !got.Equal(want) || got.Location() != time.UTC {
+		t.Errorf("readTime(stamp) = %v; want %v", got, want)
+	}
+	if got, want := app.readTime(qs, "day", time.Time{}, v), time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("readTime(day) = %v; want %v", got, want)
+	}
+	if got, want := app.readCSV(qs, "modes", nil, v), []string{"online", "evening"}; !slices.Equal(got, want) {
+		t.Errorf("readCSV(modes) = %v; want %v", got, want)
+	}
+	if !v.Valid() {
+		t.Fatalf("got errors %v for valid values", v.Errors)
+	}
+
+	// Bad values record their own error, and the numbers fall back to
+	// the default
+	if got := app.readInt(qs, "word", 7, v); got != 7 {
+		t.Errorf("readInt(word) = %d; want the default", got)
+	}
+	tests := []struct {
+		key  string
+		read func()
+		want string
+	}{
+		{"huge", func() { app.readInt(qs, "huge", 1, v) }, "integer_range"},
+		{"word", func() { app.readInt(qs, "word", 1, v) }, "integer"},
+		{"maybe", func() { app.readBool(qs, "maybe", false, v) }, "boolean"},
+		{"yesterday", func() { app.readTime(qs, "yesterday", time.Time{}, v) }, "rfc3339"},
+		{"gaps", func() { app.readCSV(qs, "gaps", nil, v) }, "empty_entry"},
+	}
+	for _, tt := range tests {
+		tt.read()
+		if got := v.Errors[tt.key]; got != tt.want {
+			t.Errorf("%s: got error %q; want %q", tt.key, got, tt.want)
+		}
+	}
+}
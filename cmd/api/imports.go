@@ -0,0 +1,85 @@
+// Filename: cmd/api/imports.go
+
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"AWD_FinalProject.ryanarmstrong.net/internal/data"
+	"AWD_FinalProject.ryanarmstrong.net/internal/importer"
+)
+
+// How long a single import run may take
+const importTimeout = 10 * time.Minute
+
+// errImportRunning is returned when an import is asked for while one is
+// already under way
+var errImportRunning = errors.New("an import is already running")
+
+// The startImport() method takes the import lock and records the run in
+// admin_jobs. Only one import runs at a time, so the lock is held until
+// runImport() is done with the job
+func (app *application) startImport(ctx context.Context) (*data.AdminJob, error) {
+	if !app.importing.TryLock() {
+		return nil, errImportRunning
+	}
+	job := &data.AdminJob{Kind: data.JobImport, StartedAt: app.clock.Now()}
+	if err := app.models.Jobs.Start(ctx, job); err != nil {
+		app.importing.Unlock()
+		return nil, err
+	}
+	return job, nil
+}
+
+// The runImport() method runs a started import, records how it went and
+// drops the cached copies of the forums it changed. It doesn't depend on
+// whoever asked for the import, so it runs on its own context
+func (app *application) runImport(job *data.AdminJob) (importer.Summary, error) {
+	defer app.importing.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), importTimeout)
+	defer cancel()
+
+	summary, err := app.importer.Run(ctx)
+	for _, id := range summary.Changed {
+		app.cache.Invalidate(id)
+		app.misses.Invalidate(id)
+	}
+	job.Summary = summary
+	if err != nil {
+		job.Error = err.Error()
+	}
+	// The record is written even when the context ran out
+	if jobErr := app.models.Jobs.Finish(context.Background(), job); jobErr != nil {
+		app.logger.Printf("recording import: %v", jobErr)
+	}
+	metrics.Add("imports", 1)
+	return summary, err
+}
+
+// The runImportScheduler() method runs an import every interval until
+// done is closed
+func (app *application) runImportScheduler(done <-chan struct{}) {
+	ticker := time.NewTicker(app.config.importer.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			job, err := app.startImport(context.Background())
+			if err != nil {
+				app.logger.Printf("scheduled import: %v", err)
+				continue
+			}
+			summary, err := app.runImport(job)
+			if err != nil {
+				app.logger.Printf("scheduled import: %v", err)
+				continue
+			}
+			app.logger.Printf("scheduled import: %d created, %d updated, %d flagged, %d errors, %d for review",
+				summary.Created, summary.Updated, summary.Flagged, summary.Errors, len(summary.Review))
+		case <-done:
+			return
+		}
+	}
+}
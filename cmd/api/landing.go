@@ -0,0 +1,42 @@
+// Filename: cmd/api/landing.go
+
+package main
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"net/http"
+)
+
+//go:embed templates/landing.html
+var templateFS embed.FS
+
+// landingTemplate is parsed once, a broken template stops the server at
+// startup rather than on a request
+var landingTemplate = template.Must(template.ParseFS(templateFS, "templates/landing.html"))
+
+// landingHandler for the "GET /" endpoint gives someone who opens the API
+// in a browser a short page pointing at the useful endpoints, instead of
+// a JSON 404
+func (app *application) landingHandler(w http.ResponseWriter, r *http.Request) {
+	var page bytes.Buffer
+	err := landingTemplate.Execute(&page, struct {
+		Version string
+		Env     string
+	}{Version: build.Version, Env: app.config.env})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	writeBody(w, r, http.StatusOK, page.Bytes())
+}
+
+// faviconHandler for the "GET /favicon.ico" endpoint answers browsers
+// with no content so they stop asking and the logs stay quiet
+func (app *application) faviconHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.WriteHeader(http.StatusNoContent)
+}
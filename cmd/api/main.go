@@ -10,32 +10,93 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"sync"
 	"time"
 
+	"AWD_FinalProject.ryanarmstrong.net/internal/cache"
 	"AWD_FinalProject.ryanarmstrong.net/internal/data"
-	_ "github.com/lib/pq"
+	"AWD_FinalProject.ryanarmstrong.net/internal/importer"
+	"AWD_FinalProject.ryanarmstrong.net/internal/moderation"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgconn/ctxwatch"
+	"github.com/jackc/pgx/v5/stdlib"
 )
 
-// The application version number
-const version = "1.0.0"
-
 // The configuration settings
 type config struct {
-	port int
-	env  string // development, staging, production, etc.
-	db   struct {
+	port    int
+	env     string // development, staging, production, etc.
+	baseURL string
+	db      struct {
 		dsn          string
+		readDSN      string
 		maxOpenConns int
 		maxIdleConns int
 		maxIdleTime  string
+		stmtCache    bool
+		slowQuery    time.Duration
+	}
+	cache struct {
+		size int
+		ttl  time.Duration
+	}
+	list struct {
+		maxOffset int
+		timeout   time.Duration
+	}
+	search struct {
+		highlightStart string
+		highlightStop  string
+	}
+	tracing struct {
+		endpoint    string
+		sampleRatio float64
+	}
+	moderation struct {
+		termsFile string
+	}
+	importer struct {
+		url      string
+		interval time.Duration
 	}
+	concurrency struct {
+		max    int
+		search int
+	}
+	tables struct {
+		monitor bool
+		names   []string
+		limit   int64
+	}
+	features features
+	// lenientInput drops read-only fields from bodies instead of refusing
+	// them. It is only kept for one release while clients catch up
+	lenientInput bool
+	errorWebhook string
 }
 
 // Dependency Injection
 type application struct {
-	config config
-	logger *log.Logger
-	models data.Models
+	config    config
+	logger    *log.Logger
+	clock     data.Clock
+	models    data.Models
+	cache     *cache.Cache[int64, data.Forum]
+	misses    *cache.Cache[int64, struct{}]
+	publicIDs *cache.Cache[string, int64]
+	// publicMisses holds the public ids recently found not to exist
+	publicMisses *cache.Cache[string, struct{}]
+	views        *viewCounter
+	moderation   *moderation.Filter
+	reporter     ErrorReporter
+	reports      reportDedup
+	importer     *importer.Importer
+	importing    sync.Mutex
+	wg           sync.WaitGroup
+	// admin reports whether a request comes from an admin. It is nil
+	// until there are accounts to check, tests set it
+	admin func(r *http.Request) bool
 }
 
 func main() {
@@ -43,15 +104,69 @@ func main() {
 	// read in the flags that are needed to populate our config
 	flag.IntVar(&cfg.port, "port", 4001, "API server port")
 	flag.StringVar(&cfg.env, "env", "development", "Environment (development | staging | production")
-	flag.StringVar(&cfg.db.dsn, "db-dsn", os.Getenv("FORUM_DB_DSN"), "PostgreSQL DSN")
+	flag.StringVar(&cfg.baseURL, "base-url", "http://localhost:4001", "Public URL of the API, used for links in the Atom feed")
+	flag.StringVar(&cfg.db.dsn, "db-dsn", "", "PostgreSQL DSN")
+	flag.StringVar(&cfg.db.readDSN, "db-read-dsn", "", "PostgreSQL read replica DSN (reads use the primary when empty)")
 	flag.IntVar(&cfg.db.maxOpenConns, "db-max-open-conns", 25, "PostgreSQL max open connections")
 	flag.IntVar(&cfg.db.maxIdleConns, "db-max-idle-conns", 25, "PostgreSQL max idle connections")
 	flag.StringVar(&cfg.db.maxIdleTime, "db-max-idle-time", "15m", "PostgreSQL max idle connections time")
+	flag.BoolVar(&cfg.db.stmtCache, "db-statement-cache", true, "Prepare and reuse statements on each connection (disable to send every query unprepared)")
+	flag.DurationVar(&cfg.db.slowQuery, "slow-query", 250*time.Millisecond, "Log queries slower than this (0 logs only failed queries)")
+	flag.IntVar(&cfg.cache.size, "cache-size", 1000, "Maximum number of forums held in the response cache (0 disables it)")
+	flag.DurationVar(&cfg.cache.ttl, "cache-ttl", time.Minute, "Time a cached forum stays fresh")
+	flag.IntVar(&cfg.list.maxOffset, "list-max-offset", 10000, "Deepest row a forum listing page may start at (0 for no limit)")
+	flag.DurationVar(&cfg.list.timeout, "list-timeout", data.DefaultListTimeout, "Server-side statement timeout for forum listings and searches")
+	flag.StringVar(&cfg.search.highlightStart, "search-highlight-start", "<b>", "Tag placed before matching terms in search headlines")
+	flag.StringVar(&cfg.search.highlightStop, "search-highlight-stop", "</b>", "Tag placed after matching terms in search headlines")
+	flag.StringVar(&cfg.tracing.endpoint, "otel-endpoint", "", "OTLP/HTTP trace exporter URL (tracing is disabled when empty)")
+	flag.Float64Var(&cfg.tracing.sampleRatio, "otel-sample-ratio", 1.0, "Fraction of new traces to sample")
+	flag.IntVar(&data.MaxInputEntries, "max-input-entries", data.MaxInputEntries, "Most elements an array in a request body may hold before decoding gives up")
+	flag.StringVar(&cfg.moderation.termsFile, "moderation-terms", "", "File of extra banned terms, one per line, added to the built-in list")
+	flag.StringVar(&cfg.importer.url, "import-url", "", "URL of the ministry registry to import forums from (imports are disabled when empty)")
+	flag.DurationVar(&cfg.importer.interval, "import-interval", 24*time.Hour, "Time between scheduled imports")
+	flag.IntVar(&cfg.concurrency.max, "max-concurrent", 200, "Most requests served at once, the rest wait briefly and then get a 503 (0 for no limit)")
+	flag.IntVar(&cfg.concurrency.search, "max-concurrent-search", 20, "Most searches run at once (0 for no limit of their own)")
+	flag.BoolVar(&cfg.tables.monitor, "table-monitor", false, "Watch the table sizes (on by default in production)")
+	flag.Func("table-monitor-tables", "Comma-separated tables the monitor watches (default forums,forum_changes,forum_views,forum_faqs,admin_jobs)", func(value string) error {
+		cfg.tables.names = splitList(value)
+		return nil
+	})
+	flag.Int64Var(&cfg.tables.limit, "table-size-limit", 1<<30, "Size in bytes past which the monitor warns about a table")
+	flag.StringVar(&cfg.errorWebhook, "error-webhook", "", "URL errors and panics are POSTed to as JSON (reporting is off when empty)")
+	flag.BoolVar(&cfg.lenientInput, "lenient-input", false, "Ignore read-only fields such as id and version in request bodies instead of answering 422 (for one release only)")
+	cfg.features.registerFlags(flag.CommandLine)
 	flag.Parse()
 	// Create a logger
 	logger := log.New(os.Stdout, "", log.Ldate|log.Ltime)
+	// Fill in the flags not given from the environment, then make sure the
+	// whole configuration makes sense before we start
+	if err := applyEnv(flag.CommandLine); err != nil {
+		logger.Fatalf("invalid environment:\n%v", err)
+	}
+	// The table monitor follows the environment unless told otherwise
+	if !isSet(flag.CommandLine, "table-monitor") {
+		cfg.tables.monitor = cfg.env == "production"
+	}
+	if cfg.tables.names == nil {
+		cfg.tables.names = []string{"forums", "forum_changes", "forum_views", "forum_faqs", "admin_jobs"}
+	}
+	if err := cfg.Validate(); err != nil {
+		logger.Fatalf("invalid configuration:\n%v", err)
+	}
+	// Load the content filter
+	filter := moderation.New()
+	if cfg.moderation.termsFile != "" {
+		if err := filter.LoadFile(cfg.moderation.termsFile); err != nil {
+			logger.Fatal(err)
+		}
+	}
+	// Set up tracing before anything starts handling requests
+	shutdownTracing, err := setupTracing(cfg)
+	if err != nil {
+		logger.Fatal(err)
+	}
 	// Create the connection pool
-	db, err := openDB(cfg)
+	db, err := openDB(cfg, cfg.db.dsn)
 	if err != nil {
 		logger.Fatal(err)
 	}
@@ -59,15 +174,48 @@ func main() {
 	defer db.Close()
 	// Log the successful connection pool
 	logger.Println("database connection pool established")
+	// Create the replica pool when one is configured
+	var readDB *sql.DB
+	if cfg.db.readDSN != "" {
+		readDB, err = openDB(cfg, cfg.db.readDSN)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		defer readDB.Close()
+		logger.Println("read replica connection pool established")
+	}
+	data.LogSlowQueries(logger, cfg.db.slowQuery)
+	// Create our models and apply the search settings
+	models := data.NewModels(db, readDB)
+	models.Forums.Highlight = data.Highlight{Start: cfg.search.highlightStart, Stop: cfg.search.highlightStop}
+	models.Forums.ListTimeout = cfg.list.timeout
+	clock := data.SystemClock{}
+	models.SetClock(clock)
 	// Create an instance of our application struct
 	app := &application{
-		config: cfg,
-		logger: logger,
-		models: data.NewModels(db),
+		config:       cfg,
+		logger:       logger,
+		clock:        clock,
+		models:       models,
+		cache:        cache.New[int64, data.Forum](cfg.cache.size, cfg.cache.ttl),
+		misses:       cache.New[int64, struct{}](cfg.cache.size, missTTL),
+		publicIDs:    cache.New[string, int64](cfg.cache.size, publicIDTTL),
+		publicMisses: cache.New[string, struct{}](cfg.cache.size, missTTL),
+		views:        newViewCounter(clock),
+		moderation:   filter,
+		reporter:     nopReporter{},
+	}
+	if cfg.errorWebhook != "" {
+		app.reporter = newWebhookReporter(cfg.errorWebhook)
+	}
+	if cfg.importer.url != "" {
+		app.importer = &importer.Importer{
+			Client:  importer.NewClient(cfg.importer.url),
+			Forums:  models.Forums,
+			Imports: models.Imports,
+			Filter:  filter,
+		}
 	}
-	// Create our new servemux
-	mux := http.NewServeMux()
-	mux.HandleFunc("/v1/healthcheck", app.healthcheckHandler)
 	// Create our HTTP server
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.port),
@@ -76,18 +224,38 @@ func main() {
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 30 * time.Second,
 	}
-	// Start our server
-	logger.Printf("Starting %s server on %s", cfg.env, srv.Addr)
-	err = srv.ListenAndServe()
-	logger.Fatal(err)
+	// Serve until we are told to shut down
+	err = app.serve(srv)
+	// Flush any spans still buffered before we exit
+	shutdownTracing(context.Background())
+	if err != nil {
+		logger.Fatal(err)
+	}
 }
 
-// The openDB() function returns a *sql.DB connection pool
-func openDB(cfg config) (*sql.DB, error) {
-	db, err := sql.Open("postgres", cfg.db.dsn)
+// The openDB() function returns a *sql.DB connection pool for the dsn
+func openDB(cfg config, dsn string) (*sql.DB, error) {
+	connConfig, err := pgx.ParseConfig(dsn)
 	if err != nil {
 		return nil, err
 	}
+	// Work in UTC so that NOW() and date arithmetic agree with what we emit
+	connConfig.RuntimeParams["timezone"] = "UTC"
+	// When a request's context is cancelled, ask the server to cancel the
+	// running query instead of only abandoning the connection, so a client
+	// that disconnects doesn't leave its query running
+	connConfig.BuildContextWatcherHandler = func(conn *pgconn.PgConn) ctxwatch.Handler {
+		return &pgconn.CancelRequestContextWatcherHandler{Conn: conn, DeadlineDelay: time.Second}
+	}
+	// pgx prepares each query the first time a connection runs it and
+	// reuses the statement after that. A statement broken by a schema change
+	// is dropped from the cache and prepared again on the next call. If that
+	// ever misbehaves the cache can be switched off and queries are sent
+	// unprepared, still over the extended protocol
+	if !cfg.db.stmtCache {
+		connConfig.DefaultQueryExecMode = pgx.QueryExecModeExec
+	}
+	db := stdlib.OpenDB(*connConfig)
 	db.SetMaxOpenConns(cfg.db.maxOpenConns)
 	db.SetMaxIdleConns(cfg.db.maxIdleConns)
 	duration, err := time.ParseDuration(cfg.db.maxIdleTime)
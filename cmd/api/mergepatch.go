@@ -0,0 +1,138 @@
+// Filename: cmd/api/mergepatch.go
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"AWD_FinalProject.ryanarmstrong.net/internal/data"
+)
+
+// The media type of a JSON Merge Patch (RFC 7386) body
+const mergePatchType = "application/merge-patch+json"
+
+// The mergePatchRequest() function reports whether the request body is a
+// JSON Merge Patch
+func mergePatchRequest(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == mergePatchType
+}
+
+// A forumDocument is the part of a forum a client can write, laid out as
+// the JSON a merge patch is applied to. A field the patch sets to null is
+// dropped from the document and so comes back as its zero value
+type forumDocument struct {
+	Name              string         `json:"name"`
+	Level             string         `json:"level"`
+	Contact           string         `json:"contact"`
+	Phones            data.Phones    `json:"phones"`
+	Email             string         `json:"email"`
+	Website           string         `json:"website"`
+	Address           string         `json:"address"`
+	Mode              data.Modes     `json:"mode"`
+	Languages         data.Languages `json:"languages"`
+	PublicContact     bool           `json:"public_contact"`
+	StudentCapacity   *int32         `json:"student_capacity"`
+	CurrentEnrollment *int32         `json:"current_enrollment"`
+	EnrollmentPrivate bool           `json:"enrollment_private"`
+}
+
+// The mergePatchForum() method applies a merge patch body to forum. The
+// result still has to pass validation, so nulling a required field ends
+// in a 422 rather than being written
+func (app *application) mergePatchForum(w http.ResponseWriter, r *http.Request, forum *data.Forum) error {
+	// readJSON() gives us the size and shape limits. A patch has to be an
+	// object, anything else would replace the whole forum
+	var patch map[string]interface{}
+	if err := app.readJSON(w, r, &patch); err != nil {
+		return err
+	}
+	if patch == nil {
+		return errors.New("body must be a JSON object")
+	}
+	doc := forumDocument{
+		Name:              forum.Name,
+		Level:             forum.Level,
+		Contact:           forum.Contact,
+		Phones:            forum.Phones,
+		Email:             forum.Email,
+		Website:           forum.Website,
+		Address:           forum.Address,
+		Mode:              forum.Mode,
+		Languages:         forum.Languages,
+		PublicContact:     forum.PublicContact,
+		StudentCapacity:   forum.StudentCapacity,
+		CurrentEnrollment: forum.CurrentEnrollment,
+		EnrollmentPrivate: forum.EnrollmentPrivate,
+	}
+	js, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	var target interface{}
+	if err := json.Unmarshal(js, &target); err != nil {
+		return err
+	}
+	merged, err := json.Marshal(mergePatch(target, patch))
+	if err != nil {
+		return err
+	}
+	// Decode the result over a fresh document so removed fields are zero
+	doc = forumDocument{}
+	dec := json.NewDecoder(bytes.NewReader(merged))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&doc); err != nil {
+		var unmarshalTypeError *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &unmarshalTypeError):
+			return fmt.Errorf("body contains incorrect JSON type for field %q", unmarshalTypeError.Field)
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			return unknownFieldError(err.Error(), &doc)
+		default:
+			return err
+		}
+	}
+	forum.Name = doc.Name
+	forum.Level = doc.Level
+	forum.Contact = doc.Contact
+	forum.Phones = doc.Phones
+	forum.Phones.Normalize()
+	forum.Email = doc.Email
+	forum.Website = doc.Website
+	forum.Address = doc.Address
+	forum.Mode = doc.Mode
+	forum.Languages = doc.Languages
+	forum.PublicContact = doc.PublicContact
+	forum.StudentCapacity = doc.StudentCapacity
+	forum.CurrentEnrollment = doc.CurrentEnrollment
+	forum.EnrollmentPrivate = doc.EnrollmentPrivate
+	return nil
+}
+
+// The mergePatch() function applies patch to target as RFC 7386 lays
+// out. Objects are merged member by member, a null member removes the
+// member and any other value replaces the target outright
+func mergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{})
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = mergePatch(targetObject[key], value)
+	}
+	return targetObject
+}
@@ -0,0 +1,126 @@
+// Filename: cmd/api/mergepatch_test.go
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"AWD_FinalProject.ryanarmstrong.net/internal/data"
+)
+
+// The cases from appendix A of RFC 7386
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		target string
+		patch  string
+		want   string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.patch, func(t *testing.T) {
+			var target, patch interface{}
+			if err := json.Unmarshal([]byte(tt.target), &target); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(tt.patch), &patch); err != nil {
+				t.Fatal(err)
+			}
+			got, err := json.Marshal(mergePatch(target, patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %s; want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergePatchRequest(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        bool
+	}{
+		{"application/merge-patch+json", true},
+		{"application/merge-patch+json; charset=utf-8", true},
+		{"Application/Merge-Patch+JSON", true},
+		{"application/json", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPatch, "/", nil)
+		r.Header.Set("Content-Type", tt.contentType)
+		if got := mergePatchRequest(r); got != tt.want {
+			t.Errorf("mergePatchRequest(%q) = %t; want %t", tt.contentType, got, tt.want)
+		}
+	}
+}
+
+// A merge patch changes the members it names, clears the ones it nulls,
+// and leaves the rest of the forum as it was
+func TestMergePatchForum(t *testing.T) {
+	patchForum := func(t *testing.T, body string) (data.Forum, error) {
+		t.Helper()
+		forum := newTestForum()
+		r := httptest.NewRequest(http.MethodPatch, "/v1/forums/1", strings.NewReader(body))
+		r.Header.Set("Content-Type", mergePatchType)
+		err := newTestApplication(t).mergePatchForum(httptest.NewRecorder(), r, &forum)
+		return forum, err
+	}
+
+	forum, err := patchForum(t, `{"name": "Night Study", "website": null, "phones": {"extension": null}, "mode": ["online", "evening"], "student_capacity": null}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if forum.Name != "Night Study" || forum.Website != "" || forum.StudentCapacity != nil {
+		t.Errorf("got name %q, website %q, capacity %v; want the patched values", forum.Name, forum.Website, forum.StudentCapacity)
+	}
+	if forum.Phones.Extension != "" || forum.Phones.Primary == "" {
+		t.Errorf("got phones %+v; want the extension cleared and the number kept", forum.Phones)
+	}
+	if !slices.Equal(forum.Mode, data.Modes{"online", "evening"}) {
+		t.Errorf("got mode %v; want the array replaced", forum.Mode)
+	}
+	if forum.Level != "University" || forum.Email != "study@example.com" || !slices.Equal(forum.Languages, data.Languages{"en"}) {
+		t.Errorf("got %+v; want the members the patch left out unchanged", forum)
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"not an object", `["name"]`, "body contains incorrect JSON type (at character 1)"},
+		{"null", `null`, "body must be a JSON object"},
+		{"wrong type", `{"name": 12}`, `body contains incorrect JSON type for field "name"`},
+		{"unknown key", `{"nmae": "x"}`, `body contains unknown key "nmae", did you mean "name"?`},
+		{"read only", `{"id": 3}`, `body contains unknown key "id"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := patchForum(t, tt.body); err == nil || err.Error() != tt.want {
+				t.Errorf("got error %v; want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+// Filename: cmd/api/metrics.go
+
+package main
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+
+	"AWD_FinalProject.ryanarmstrong.net/internal/data"
+)
+
+// The metrics map holds our counters. It is published through expvar
+// under "api", but we serve it on its own because the full expvar page
+// would also show the command line, DSN included
+var metrics = expvar.NewMap("api")
+
+// metricsHandler for the "GET /v1/metrics" endpoint returns the counters
+// and the query timings
+func (app *application) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", jsonContentType)
+	w.Header().Set("Cache-Control", "no-store")
+	body := fmt.Sprintf("{\"api\": %s, \"db_queries\": %s}\n", metrics.String(), data.QueryMetrics.String())
+	writeBody(w, r, http.StatusOK, []byte(body))
+}
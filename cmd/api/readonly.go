@@ -0,0 +1,62 @@
+// Filename: cmd/api/readonly.go
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+const readOnlyContextKey = contextKey("read_only")
+
+// A readOnlyError lists the read-only fields a request body tried to set
+type readOnlyError struct {
+	fields []string
+}
+
+func (e *readOnlyError) Error() string {
+	return "body sets read-only fields " + strings.Join(e.fields, ", ")
+}
+
+// The withReadOnly() middleware tells readJSON() which fields of the
+// route's body are read-only
+func (app *application) withReadOnly(fields []string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), readOnlyContextKey, fields)
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// The rejectReadOnly() method checks the top level of a body for the
+// route's read-only fields. They are refused with a readOnlyError, or
+// with -lenient-input quietly dropped and the trimmed body returned
+func (app *application) rejectReadOnly(r *http.Request, body []byte) ([]byte, error) {
+	readOnly, _ := r.Context().Value(readOnlyContextKey).([]string)
+	if len(readOnly) == 0 {
+		return body, nil
+	}
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(body, &fields) != nil {
+		return body, nil
+	}
+	var found []string
+	for _, field := range readOnly {
+		if _, ok := fields[field]; ok {
+			found = append(found, field)
+		}
+	}
+	if len(found) == 0 {
+		return body, nil
+	}
+	if !app.config.lenientInput {
+		slices.Sort(found)
+		return nil, &readOnlyError{fields: found}
+	}
+	for _, field := range found {
+		delete(fields, field)
+	}
+	return json.Marshal(fields)
+}
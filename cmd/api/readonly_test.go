@@ -0,0 +1,90 @@
+// Filename: cmd/api/readonly_test.go
+
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+// Only the top level of a body is checked, and only on routes that name
+// read-only fields
+func TestRejectReadOnly(t *testing.T) {
+	app := newTestApplication(t)
+	readOnly := []string{"version", "id"}
+	check := func(fields []string, body string) ([]byte, error) {
+		var got []byte
+		var err error
+		h := app.withReadOnly(fields, func(w http.ResponseWriter, r *http.Request) {
+			got, err = app.rejectReadOnly(r, []byte(body))
+		})
+		h(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", nil))
+		return got, err
+	}
+
+	tests := []struct {
+		name   string
+		fields []string
+		body   string
+		want   []string
+	}{
+		{"clean body", readOnly, `{"name": "Study Group"}`, nil},
+		{"one field", readOnly, `{"name": "Study Group", "id": 1}`, []string{"id"}},
+		{"sorted", readOnly, `{"version": 2, "id": 1}`, []string{"id", "version"}},
+		{"nested", readOnly, `{"phones": {"id": 1}}`, nil},
+		{"not an object", readOnly, `["id"]`, nil},
+		{"route without any", nil, `{"id": 1}`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := check(tt.fields, tt.body)
+			var readOnlyErr *readOnlyError
+			if tt.want == nil {
+				if err != nil || string(got) != tt.body {
+					t.Errorf("got %s, %v; want the body back untouched", got, err)
+				}
+				return
+			}
+			if !errors.As(err, &readOnlyErr) || !slices.Equal(readOnlyErr.fields, tt.want) {
+				t.Errorf("got error %v; want the fields %v", err, tt.want)
+			}
+		})
+	}
+
+	app.config.lenientInput = true
+	got, err := check(readOnly, `{"name": "Study Group", "id": 1, "version": 2}`)
+	if err != nil || string(got) != `{"name":"Study Group"}` {
+		t.Errorf("got %s, %v with -lenient-input; want the fields dropped", got, err)
+	}
+}
+
+// Through the routes a read-only field is a 422 keyed on the field. With
+// -lenient-input it is dropped and the rest of the body is checked as
+// usual
+func TestCreateForumReadOnly(t *testing.T) {
+	app := newTestApplication(t)
+	rr := send(t, app.routes(), http.MethodPost, "/v1/forums", `{"id": 1, "version": 3, "name": ""}`)
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("got status %d; want %d", rr.Code, http.StatusUnprocessableEntity)
+	}
+	fields := errorFields(t, rr)
+	if fields["id"] != "this field cannot be set" || fields["version"] != "this field cannot be set" {
+		t.Errorf("got errors %v; want id and version refused", fields)
+	}
+
+	app.config.lenientInput = true
+	rr = send(t, app.routes(), http.MethodPost, "/v1/forums", `{"id": 1, "version": 3, "name": ""}`)
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("got status %d with -lenient-input; want %d", rr.Code, http.StatusUnprocessableEntity)
+	}
+	fields = errorFields(t, rr)
+	if _, ok := fields["id"]; ok {
+		t.Errorf("got errors %v with -lenient-input; want id dropped", fields)
+	}
+	if _, ok := fields["name"]; !ok {
+		t.Errorf("got errors %v with -lenient-input; want the rest of the body checked", fields)
+	}
+}
@@ -0,0 +1,205 @@
+// Filename: cmd/api/reporter.go
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// An identical error is only reported once in this window, so a fault
+// hit by every request doesn't flood the tracker
+const reportWindow = time.Minute
+
+// An ErrorReporter sends errors to an external tracker. meta holds what
+// we know about where the error happened
+type ErrorReporter interface {
+	Report(ctx context.Context, err error, meta map[string]interface{}) error
+}
+
+// nopReporter is used when no tracker is configured. reportError() skips
+// it, so nothing is counted as reported
+type nopReporter struct{}
+
+func (nopReporter) Report(context.Context, error, map[string]interface{}) error { return nil }
+
+// A webhookReporter POSTs each error as JSON to a URL
+type webhookReporter struct {
+	url    string
+	client *http.Client
+}
+
+func newWebhookReporter(url string) *webhookReporter {
+	return &webhookReporter{url: url, client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+func (wr *webhookReporter) Report(ctx context.Context, err error, meta map[string]interface{}) error {
+	body, jsonErr := json.Marshal(map[string]interface{}{
+		"error":       err.Error(),
+		"meta":        meta,
+		"reported_at": time.Now().UTC().Format(time.RFC3339),
+	})
+	if jsonErr != nil {
+		return jsonErr
+	}
+	req, reqErr := http.NewRequestWithContext(ctx, http.MethodPost, wr.url, bytes.NewReader(body))
+	if reqErr != nil {
+		return reqErr
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, reqErr := wr.client.Do(req)
+	if reqErr != nil {
+		return reqErr
+	}
+	res.Body.Close()
+	if res.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("error webhook answered %s", res.Status)
+	}
+	return nil
+}
+
+// The reportDedup type remembers when each error was last reported
+type reportDedup struct {
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+// The allow() method reports whether an error with the key may be sent
+// now, and if so starts a new window for it
+func (d *reportDedup) allow(key string, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.last == nil {
+		d.last = make(map[string]time.Time)
+	}
+	if last, ok := d.last[key]; ok && now.Sub(last) < reportWindow {
+		return false
+	}
+	// Drop the windows that have closed so the map stays small
+	for k, t := range d.last {
+		if now.Sub(t) >= reportWindow {
+			delete(d.last, k)
+		}
+	}
+	d.last[key] = now
+	return true
+}
+
+// The reportError() method hands an error to the reporter in the
+// background, together with the running version. Repeats within
+// reportWindow are dropped. Only reports the tracker took are counted in
+// errors_reported
+func (app *application) reportError(err error, meta map[string]interface{}) {
+	if _, ok := app.reporter.(nopReporter); ok || app.reporter == nil {
+		return
+	}
+	if meta == nil {
+		meta = make(map[string]interface{})
+	}
+	meta["version"] = build.Version
+	meta["environment"] = app.config.env
+	if !app.reports.allow(err.Error(), app.clock.Now()) {
+		metrics.Add("errors_deduplicated", 1)
+		return
+	}
+	app.background(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if reportErr := app.reporter.Report(ctx, err, meta); reportErr != nil {
+			metrics.Add("errors_report_failed", 1)
+			app.logger.Printf("reporting error: %v", reportErr)
+			return
+		}
+		metrics.Add("errors_reported", 1)
+	})
+}
+
+// A requestInfo holds what an error report needs to know about the
+// request it came from. The route is filled in once the router has
+// matched one
+type requestInfo struct {
+	id    string
+	route string
+}
+
+const requestInfoContextKey = contextKey("request_info")
+
+// An X-Request-Id we are sent is kept when it looks like an id
+var requestIDRX = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// The identifyRequest() middleware gives each request an id, the one the
+// client or a proxy sent in X-Request-Id or a new random one, and echoes
+// it back in the response
+func (app *application) identifyRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-Id")
+		if !requestIDRX.MatchString(id) {
+			id = newRequestID()
+		}
+		w.Header().Set("X-Request-Id", id)
+		ctx := context.WithValue(r.Context(), requestInfoContextKey, &requestInfo{id: id})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// The newRequestID() function draws a random 128-bit id
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// The withRoute() function notes the route pattern a request matched
+func withRoute(route string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if info, ok := r.Context().Value(requestInfoContextKey).(*requestInfo); ok {
+			info.route = route
+		}
+		next(w, r)
+	}
+}
+
+// The requestMeta() function describes a request for an error report.
+// The route pattern is sent rather than the path, which can carry ids
+func requestMeta(r *http.Request) map[string]interface{} {
+	meta := map[string]interface{}{
+		"method": r.Method,
+	}
+	if info, ok := r.Context().Value(requestInfoContextKey).(*requestInfo); ok {
+		meta["request_id"] = info.id
+		if info.route != "" {
+			meta["route"] = info.route
+		}
+	}
+	return meta
+}
+
+// The recoverPanic() middleware turns a panic in a handler into a 500
+// and a report instead of a dropped connection
+func (app *application) recoverPanic(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if p := recover(); p != nil {
+				if p == http.ErrAbortHandler {
+					panic(p)
+				}
+				err := fmt.Errorf("panic: %v", p)
+				meta := requestMeta(r)
+				meta["panic"] = true
+				app.reportError(err, meta)
+				w.Header().Set("Connection", "close")
+				app.logError(r, err)
+				app.errorResponse(w, r, http.StatusInternalServerError, app.translator(r).T("server_error"))
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
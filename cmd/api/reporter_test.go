@@ -0,0 +1,186 @@
+// Filename: cmd/api/reporter_test.go
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// A fakeReporter keeps what it is sent, and fails every report with err
+// when one is set
+type fakeReporter struct {
+	mu    sync.Mutex
+	err   error
+	sent  []error
+	metas []map[string]interface{}
+}
+
+func (f *fakeReporter) Report(ctx context.Context, err error, meta map[string]interface{}) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, err)
+	f.metas = append(f.metas, meta)
+	return f.err
+}
+
+func TestReportDedup(t *testing.T) {
+	var d reportDedup
+	start := time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		key  string
+		at   time.Duration
+		want bool
+	}{
+		{"db down", 0, true},
+		{"db down", 30 * time.Second, false},
+		{"disk full", 30 * time.Second, true},
+		{"db down", reportWindow - time.Nanosecond, false},
+		// The window runs from the report that was sent, not the last
+		// one dropped
+		{"db down", reportWindow, true},
+		{"disk full", reportWindow + 10*time.Second, false},
+	}
+	for _, tt := range tests {
+		if got := d.allow(tt.key, start.Add(tt.at)); got != tt.want {
+			t.Errorf("allow(%q) after %v = %t; want %t", tt.key, tt.at, got, tt.want)
+		}
+	}
+	// Closed windows are cleared out as new ones open
+	d.allow("timeout", start.Add(3*reportWindow))
+	if len(d.last) != 1 {
+		t.Errorf("got %d keys remembered; want 1", len(d.last))
+	}
+}
+
+// Only reports the tracker took count as reported. A repeat inside the
+// window is dropped and counted as such
+func TestReportError(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.env = "staging"
+	reporter := &fakeReporter{}
+	app.reporter = reporter
+
+	reported, deduplicated := metricValue("errors_reported"), metricValue("errors_deduplicated")
+	app.reportError(errors.New("db down"), map[string]interface{}{"route": "/v1/forums"})
+	app.reportError(errors.New("db down"), nil)
+	app.wg.Wait()
+	if len(reporter.sent) != 1 {
+		t.Fatalf("got %d reports; want 1", len(reporter.sent))
+	}
+	meta := reporter.metas[0]
+	if meta["route"] != "/v1/forums" || meta["environment"] != "staging" || meta["version"] == nil {
+		t.Errorf("got meta %v; want the route, environment and version", meta)
+	}
+	if got := metricValue("errors_reported") - reported; got != 1 {
+		t.Errorf("errors_reported went up by %d; want 1", got)
+	}
+	if got := metricValue("errors_deduplicated") - deduplicated; got != 1 {
+		t.Errorf("errors_deduplicated went up by %d; want 1", got)
+	}
+
+	reporter.err = errors.New("tracker unavailable")
+	reported, failed := metricValue("errors_reported"), metricValue("errors_report_failed")
+	app.reportError(errors.New("disk full"), nil)
+	app.wg.Wait()
+	if got := metricValue("errors_report_failed") - failed; got != 1 {
+		t.Errorf("errors_report_failed went up by %d; want 1", got)
+	}
+	if got := metricValue("errors_reported") - reported; got != 0 {
+		t.Errorf("errors_reported went up by %d for a failed report; want 0", got)
+	}
+}
+
+// With no tracker configured nothing is sent or counted
+func TestReportErrorNop(t *testing.T) {
+	app := newTestApplication(t)
+	deduplicated := metricValue("errors_deduplicated")
+	app.reportError(errors.New("db down"), nil)
+	app.reportError(errors.New("db down"), nil)
+	app.wg.Wait()
+	if got := metricValue("errors_deduplicated") - deduplicated; got != 0 {
+		t.Errorf("errors_deduplicated went up by %d; want 0", got)
+	}
+	if app.reports.last != nil {
+		t.Error("the nop reporter went through deduplication")
+	}
+}
+
+// A sensible X-Request-Id is passed on, anything else is replaced with
+// a fresh id. Either way it is echoed back and ends up in the report
+func TestIdentifyRequest(t *testing.T) {
+	app := newTestApplication(t)
+	var meta map[string]interface{}
+	h := app.identifyRequest(withRoute("/v1/forums/:id", func(w http.ResponseWriter, r *http.Request) {
+		meta = requestMeta(r)
+	}))
+	generated := regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+	tests := []struct {
+		name string
+		sent string
+		keep bool
+	}{
+		{"valid", "req-42.a_b", true},
+		{"missing", "", false},
+		{"spaces", "req 42", false},
+		{"too long", strings.Repeat("a", 65), false},
+		{"header injection", "req\r\nSet-Cookie: x", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/v1/forums/42", nil)
+			if tt.sent != "" {
+				r.Header.Set("X-Request-Id", tt.sent)
+			}
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, r)
+			id := rr.Header().Get("X-Request-Id")
+			if tt.keep && id != tt.sent {
+				t.Errorf("got id %q; want the one sent, %q", id, tt.sent)
+			}
+			if !tt.keep && !generated.MatchString(id) {
+				t.Errorf("got id %q; want 32 hex characters", id)
+			}
+			if meta["request_id"] != id || meta["route"] != "/v1/forums/:id" || meta["method"] != http.MethodGet {
+				t.Errorf("got meta %v; want the id, route and method", meta)
+			}
+		})
+	}
+
+	// Outside identifyRequest() there is only the method to go on
+	meta = requestMeta(httptest.NewRequest(http.MethodPost, "/", nil))
+	if len(meta) != 1 || meta["method"] != http.MethodPost {
+		t.Errorf("got meta %v; want only the method", meta)
+	}
+}
+
+// A panicking handler gets a 500 and is reported with its route
+func TestRecoverPanic(t *testing.T) {
+	app := newTestApplication(t)
+	reporter := &fakeReporter{}
+	app.reporter = reporter
+	srv := app.identifyRequest(app.recoverPanic(app.router([]route{
+		{method: http.MethodGet, path: "/v1/broken/:id", handler: func(w http.ResponseWriter, r *http.Request) {
+			panic("nil map")
+		}},
+	})))
+	rr := send(t, srv, http.MethodGet, "/v1/broken/7", "")
+	app.wg.Wait()
+	if rr.Code != http.StatusInternalServerError || rr.Header().Get("Connection") != "close" {
+		t.Errorf("got status %d, Connection %q; want a 500 that closes", rr.Code, rr.Header().Get("Connection"))
+	}
+	if len(reporter.sent) != 1 || reporter.sent[0].Error() != "panic: nil map" {
+		t.Fatalf("got reports %v; want the panic", reporter.sent)
+	}
+	if meta := reporter.metas[0]; meta["panic"] != true || meta["route"] != "/v1/broken/:id" {
+		t.Errorf("got meta %v; want the panic flag and route", meta)
+	}
+}
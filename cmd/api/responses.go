@@ -0,0 +1,94 @@
+// Filename: cmd/api/responses.go
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"AWD_FinalProject.ryanarmstrong.net/internal/data"
+)
+
+// The shapes of our successful responses, all built on writeJSON():
+//
+//	writeResource():   {"forum": {...}}
+//	writeCollection(): {"forums": [...], "metadata": {...}}
+//	writeMessage():    {"message": "..."}
+//
+// With ?envelope=false resources and collections are sent bare and a
+// collection's metadata moves into response headers
+
+// The wantsEnvelope() function reports whether the response should be
+// wrapped in its envelope key. Only an explicit false turns it off
+func wantsEnvelope(r *http.Request) bool {
+	value := r.URL.Query().Get("envelope")
+	if value == "" {
+		return true
+	}
+	envelope, err := strconv.ParseBool(value)
+	return err != nil || envelope
+}
+
+// The writeResource() method sends a single value under key
+func (app *application) writeResource(w http.ResponseWriter, r *http.Request, status int, key string, value interface{}, headers http.Header) error {
+	if !wantsEnvelope(r) {
+		return app.writeJSON(w, r, status, value, headers)
+	}
+	return app.writeJSON(w, r, status, envelope{key: value}, headers)
+}
+
+// The writeCollection() method sends a list under key along with its
+// pagination metadata. A nil metadata leaves the "metadata" key out
+func (app *application) writeCollection(w http.ResponseWriter, r *http.Request, status int, key string, items interface{}, metadata interface{}, headers http.Header) error {
+	if !wantsEnvelope(r) {
+		if headers == nil {
+			headers = make(http.Header)
+		}
+		if err := metadataHeaders(headers, metadata); err != nil {
+			return err
+		}
+		return app.writeJSON(w, r, status, items, headers)
+	}
+	env := envelope{key: items}
+	if metadata != nil {
+		env["metadata"] = metadata
+	}
+	return app.writeJSON(w, r, status, env, headers)
+}
+
+// The metadataHeaders() function moves collection metadata into headers
+// for bare responses. Pagination and the changes cursor get a header per
+// field and anything else travels as compact JSON in X-Metadata
+func metadataHeaders(headers http.Header, metadata interface{}) error {
+	switch m := metadata.(type) {
+	case nil:
+		return nil
+	case data.Metadata:
+		headers.Set("X-Total-Count", strconv.Itoa(m.TotalRecords))
+		headers.Set("X-Page", strconv.Itoa(m.CurrentPage))
+		headers.Set("X-Page-Size", strconv.Itoa(m.PageSize))
+		headers.Set("X-Last-Page", strconv.Itoa(m.LastPage))
+		if m.Facets != nil {
+			js, err := json.Marshal(m.Facets)
+			if err != nil {
+				return err
+			}
+			headers.Set("X-Facets", string(js))
+		}
+	case changesMetadata:
+		headers.Set("X-Next-Cursor", m.NextCursor)
+	default:
+		js, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+		headers.Set("X-Metadata", string(js))
+	}
+	return nil
+}
+
+// The writeMessage() method sends a short human-readable confirmation
+func (app *application) writeMessage(w http.ResponseWriter, r *http.Request, status int, text string) error {
+	return app.writeJSON(w, r, status, envelope{"message": text}, nil)
+}
@@ -3,22 +3,239 @@
 package main
 
 import (
+	"mime"
 	"net/http"
+	"path"
+	"slices"
+	"sort"
+	"strings"
 
+	"AWD_FinalProject.ryanarmstrong.net/internal/data"
 	"github.com/julienschmidt/httprouter"
 )
 
-func (app *application) routes() *httprouter.Router {
+// A route is one entry in the route table. Static names that share the
+// position of the path's last wildcard go in static, see staticFirst().
+// accepts lists the body types a write route takes, JSON when empty.
+// readOnly names the body fields the route refuses to take. An expensive
+// route can hold a concurrency limit of its own, on top of the
+// server-wide one
+type route struct {
+	method      string
+	path        string
+	handler     http.HandlerFunc
+	static      map[string]http.HandlerFunc
+	accepts     []string
+	readOnly    []string
+	concurrency int
+}
+
+// The routeTable() method lists every route we serve. Optional areas are
+// only listed when switched on, so a disabled one answers 404 like any
+// unknown path
+func (app *application) routeTable() []route {
+	features := app.config.features
+	// The static names served from the /v1/forums/:id position
+	forumStatic := map[string]http.HandlerFunc{
+		"schema":  app.showForumSchemaHandler,
+		"changes": app.listForumChangesHandler,
+	}
+	if features.feed {
+		forumStatic["feed.atom"] = app.recentForumsFeedHandler
+	}
+	if features.compare {
+		forumStatic["compare"] = app.compareForumsHandler
+	}
+
+	forumReadOnly := data.ForumReadOnlyFields()
+	routes := []route{
+		{method: http.MethodGet, path: "/", handler: app.landingHandler},
+		{method: http.MethodGet, path: "/favicon.ico", handler: app.faviconHandler},
+		{method: http.MethodGet, path: "/v1/healthcheck", handler: app.healthcheckHandler},
+		{method: http.MethodGet, path: "/v1/version", handler: app.versionHandler},
+		{method: http.MethodGet, path: "/v1/metrics", handler: app.metricsHandler},
+		{method: http.MethodGet, path: "/v1/features", handler: app.featuresHandler},
+		{method: http.MethodGet, path: "/v1/forums", handler: app.listForumsHandler},
+		{method: http.MethodPost, path: "/v1/forums", handler: app.createForumHandler, readOnly: forumReadOnly},
+		{method: http.MethodGet, path: "/v1/forums/:id", handler: app.showForumHandler, static: forumStatic},
+		{method: http.MethodPatch, path: "/v1/forums/:id", handler: app.updateForumHandler, accepts: []string{"application/json", mergePatchType}, readOnly: forumReadOnly},
+		{method: http.MethodDelete, path: "/v1/forums/:id", handler: app.deleteForumHandler},
+		{method: http.MethodPost, path: "/v1/forums/:id/renew", handler: app.renewForumHandler},
+		{method: http.MethodPost, path: "/v1/forums/:id/archive", handler: app.archiveForumHandler},
+		{method: http.MethodPost, path: "/v1/forums/:id/unarchive", handler: app.unarchiveForumHandler},
+	}
+	if features.search {
+		routes = append(routes, route{method: http.MethodGet, path: "/v1/search", handler: app.searchHandler, concurrency: app.config.concurrency.search})
+	}
+	if features.faqs {
+		routes = append(routes,
+			route{method: http.MethodPost, path: "/v1/forums/:id/faqs", handler: app.createFAQHandler, readOnly: data.FAQReadOnlyFields},
+			route{method: http.MethodPatch, path: "/v1/forums/:id/faqs/:faq_id", handler: app.updateFAQHandler, readOnly: data.FAQReadOnlyFields, static: map[string]http.HandlerFunc{
+				"order": app.reorderFAQsHandler,
+			}},
+			route{method: http.MethodDelete, path: "/v1/forums/:id/faqs/:faq_id", handler: app.deleteFAQHandler},
+		)
+	}
+	// The route table itself is only for looking at during development
+	if app.config.env == "development" {
+		routes = append(routes, route{method: http.MethodGet, path: "/v1/routes", handler: app.listRoutesHandler})
+	}
+	return routes
+}
+
+func (app *application) routes() http.Handler {
+	router := app.router(app.routeTable())
+	limited := app.limitConcurrency("requests", app.config.concurrency.max, app.normalizePath(app.trackDeprecations(router)))
+	return app.trackResponse(app.identifyRequest(app.recoverPanic(app.apiVersion(limited))))
+}
+
+// The router() method registers a route table with a new httprouter
+// router. httprouter panics on a registration that conflicts with one
+// already made
+func (app *application) router(table []route) *httprouter.Router {
 	// Create a new httprouter router instance
 	router := httprouter.New()
 	router.NotFound = http.HandlerFunc(app.notFoundResponse)
 	router.MethodNotAllowed = http.HandlerFunc(app.methodNotAllowedResponse)
-	router.HandlerFunc(http.MethodGet, "/v1/healthcheck", app.healthcheckHandler)
-	router.HandlerFunc(http.MethodGet, "/v1/forums", app.listForumsHandler)
-	router.HandlerFunc(http.MethodPost, "/v1/forums", app.createForumHandler)
-	router.HandlerFunc(http.MethodGet, "/v1/forums/:id", app.showForumHandler)
-	router.HandlerFunc(http.MethodPatch, "/v1/forums/:id", app.updateForumHandler)
-	router.HandlerFunc(http.MethodDelete, "/v1/forums/:id", app.deleteForumHandler)
-
+	// normalizePath() has already dealt with stray slashes
+	router.RedirectTrailingSlash = false
+	router.RedirectFixedPath = false
+	// Every route gets a trace span named by its route pattern. httprouter
+	// doesn't answer HEAD by itself, so every GET route is also registered
+	// for HEAD and the write helpers leave the body off
+	for _, rt := range table {
+		handler := rt.handler
+		if rt.static != nil {
+			handler = app.staticFirst(lastParam(rt.path), handler, rt.static)
+		}
+		if writeMethod(rt.method) {
+			accepts := rt.accepts
+			if accepts == nil {
+				accepts = []string{"application/json"}
+			}
+			handler = app.requireContentType(accepts, handler)
+		}
+		if rt.readOnly != nil {
+			handler = app.withReadOnly(rt.readOnly, handler)
+		}
+		if rt.concurrency > 0 {
+			// HEAD shares the limit with GET
+			handler = app.limitConcurrency(path.Base(rt.path), rt.concurrency, handler).ServeHTTP
+		}
+		handler = withRoute(rt.path, handler)
+		router.HandlerFunc(rt.method, rt.path, app.trace(rt.path, handler))
+		if rt.method == http.MethodGet {
+			router.HandlerFunc(http.MethodHead, rt.path, app.trace(rt.path, handler))
+		}
+	}
 	return router
 }
+
+// The normalizePath() middleware collapses repeated slashes before the
+// router sees the path, so "//v1/forums" is served as "/v1/forums". A
+// trailing slash is redirected away instead, with the query string kept:
+// 301 for GET and HEAD, 308 for everything else so the client sends the
+// body again rather than turning the request into a GET
+func (app *application) normalizePath(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+		for strings.Contains(path, "//") {
+			path = strings.ReplaceAll(path, "//", "/")
+		}
+		if path != r.URL.Path {
+			r.URL.Path = path
+			r.URL.RawPath = ""
+		}
+		if len(path) > 1 && strings.HasSuffix(path, "/") {
+			target := *r.URL
+			target.Path = strings.TrimRight(path, "/")
+			code := http.StatusPermanentRedirect
+			if r.Method == http.MethodGet || r.Method == http.MethodHead {
+				code = http.StatusMovedPermanently
+			}
+			metrics.Add("path_redirects", 1)
+			http.Redirect(w, r, target.RequestURI(), code)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// The writeMethod() function reports whether requests with the method
+// carry a body we read
+func writeMethod(method string) bool {
+	return method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch
+}
+
+// The requireContentType() middleware refuses a request body that isn't
+// one of the accepted types with a 415, rather than letting the decoder
+// fail on it. Parameters such as charset are allowed. A request without a
+// body has nothing to check
+func (app *application) requireContentType(accepts []string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength == 0 {
+			next(w, r)
+			return
+		}
+		mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || !slices.Contains(accepts, mediaType) {
+			app.unsupportedMediaTypeResponse(w, r, accepts)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// The lastParam() function returns the name of the last wildcard in a
+// route path, "id" for "/v1/forums/:id"
+func lastParam(path string) string {
+	i := strings.LastIndex(path, "/:")
+	if i < 0 {
+		panic("route " + path + " has static names but no wildcard")
+	}
+	return strings.SplitN(path[i+2:], "/", 2)[0]
+}
+
+// listRoutesHandler for the "GET /v1/routes" endpoint dumps the route
+// table, static names included, sorted by path and method
+func (app *application) listRoutesHandler(w http.ResponseWriter, r *http.Request) {
+	type entry struct {
+		Method string `json:"method"`
+		Path   string `json:"path"`
+	}
+	entries := []entry{}
+	for _, rt := range app.routeTable() {
+		entries = append(entries, entry{Method: rt.method, Path: rt.path})
+		if rt.static != nil {
+			prefix := rt.path[:strings.LastIndex(rt.path, "/:")+1]
+			for name := range rt.static {
+				entries = append(entries, entry{Method: rt.method, Path: prefix + name})
+			}
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Path != entries[j].Path {
+			return entries[i].Path < entries[j].Path
+		}
+		return entries[i].Method < entries[j].Method
+	})
+	err := app.writeCollection(w, r, http.StatusOK, "routes", entries, nil, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// httprouter does not allow a static path segment in the same position as a
+// wildcard, so routes like "/v1/forums/schema" can't be registered next to
+// "/v1/forums/:id". The staticFirst() method lets the wildcard route dispatch
+// to the static handlers when the named parameter matches one of their names
+func (app *application) staticFirst(param string, next http.HandlerFunc, static map[string]http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := httprouter.ParamsFromContext(r.Context())
+		if handler, ok := static[params.ByName(param)]; ok {
+			handler(w, r)
+			return
+		}
+		next(w, r)
+	}
+}
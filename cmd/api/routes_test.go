@@ -0,0 +1,258 @@
+// Filename: cmd/api/routes_test.go
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"AWD_FinalProject.ryanarmstrong.net/internal/importer"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Every path we serve should reach the handler meant for it. The static
+// names sharing the :id position are the ones most likely to go wrong
+func TestRoutesResolve(t *testing.T) {
+	app := newTestApplication(t)
+	router := app.router(stubRoutes(app.routeTable()))
+
+	tests := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{http.MethodGet, "/", "landingHandler"},
+		{http.MethodGet, "/favicon.ico", "faviconHandler"},
+		{http.MethodGet, "/v1/healthcheck", "healthcheckHandler"},
+		{http.MethodGet, "/v1/version", "versionHandler"},
+		{http.MethodGet, "/v1/metrics", "metricsHandler"},
+		{http.MethodGet, "/v1/features", "featuresHandler"},
+		{http.MethodGet, "/v1/routes", "listRoutesHandler"},
+		{http.MethodGet, "/v1/forums", "listForumsHandler"},
+		{http.MethodPost, "/v1/forums", "createForumHandler"},
+		{http.MethodGet, "/v1/forums/42", "showForumHandler"},
+		{http.MethodGet, "/v1/forums/frm_3kq9x2", "showForumHandler"},
+		{http.MethodHead, "/v1/forums/42", "showForumHandler"},
+		{http.MethodPatch, "/v1/forums/42", "updateForumHandler"},
+		{http.MethodDelete, "/v1/forums/42", "deleteForumHandler"},
+		{http.MethodPost, "/v1/forums/42/renew", "renewForumHandler"},
+		{http.MethodPost, "/v1/forums/42/archive", "archiveForumHandler"},
+		{http.MethodPost, "/v1/forums/42/unarchive", "unarchiveForumHandler"},
+		// The static names in the :id position
+		{http.MethodGet, "/v1/forums/schema", "showForumSchemaHandler"},
+		{http.MethodHead, "/v1/forums/schema", "showForumSchemaHandler"},
+		{http.MethodGet, "/v1/forums/changes", "listForumChangesHandler"},
+		{http.MethodGet, "/v1/forums/feed.atom", "recentForumsFeedHandler"},
+		{http.MethodGet, "/v1/forums/compare", "compareForumsHandler"},
+		// Only an exact name is static, anything else is an id
+		{http.MethodGet, "/v1/forums/schemas", "showForumHandler"},
+		{http.MethodGet, "/v1/forums/Schema", "showForumHandler"},
+		// The static names are only GET routes, other methods go to the
+		// :id handler as usual
+		{http.MethodDelete, "/v1/forums/schema", "deleteForumHandler"},
+		{http.MethodPatch, "/v1/forums/compare", "updateForumHandler"},
+		{http.MethodGet, "/v1/search", "searchHandler"},
+		{http.MethodPost, "/v1/forums/42/faqs", "createFAQHandler"},
+		{http.MethodPatch, "/v1/forums/42/faqs/7", "updateFAQHandler"},
+		{http.MethodPatch, "/v1/forums/42/faqs/order", "reorderFAQsHandler"},
+		{http.MethodDelete, "/v1/forums/42/faqs/7", "deleteFAQHandler"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(tt.method, tt.path, nil))
+			if rr.Code != http.StatusOK {
+				t.Fatalf("got status %d; want %d", rr.Code, http.StatusOK)
+			}
+			if got := rr.Body.String(); got != tt.want {
+				t.Errorf("got handler %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// Every entry in the route table, and every static name in it, should be
+// reachable through the router. This catches a route added to the table
+// that something else shadows
+func TestRouteTableReachable(t *testing.T) {
+	app := newTestApplication(t)
+	table := app.routeTable()
+	router := app.router(stubRoutes(table))
+
+	for _, rt := range table {
+		path := fillParams(rt.path)
+		checkRoute(t, router, rt.method, path, handlerName(rt.handler))
+		if rt.method == http.MethodGet {
+			checkRoute(t, router, http.MethodHead, path, handlerName(rt.handler))
+		}
+		prefix := fillParams(rt.path[:strings.LastIndex(rt.path, "/")+1])
+		for name, h := range rt.static {
+			checkRoute(t, router, rt.method, prefix+name, handlerName(h))
+		}
+	}
+}
+
+// The table for every combination of switched-off areas should register
+// without httprouter finding a conflict, and without two entries for the
+// same method and path
+func TestRouteTableConflicts(t *testing.T) {
+	flags := []string{"-enable-faqs", "-enable-views", "-enable-feed", "-enable-search", "-enable-compare"}
+	for mask := 0; mask < 1<<len(flags); mask++ {
+		var args []string
+		for i, f := range flags {
+			args = append(args, f+"="+strconv.FormatBool(mask&(1<<i) == 0))
+		}
+		t.Run(strings.Join(args, " "), func(t *testing.T) {
+			app := newTestApplication(t, args...)
+			table := app.routeTable()
+
+			seen := make(map[string]bool)
+			for _, rt := range table {
+				names := []string{rt.path}
+				prefix := rt.path[:strings.LastIndex(rt.path, "/")+1]
+				for name := range rt.static {
+					names = append(names, prefix+name)
+				}
+				for _, name := range names {
+					key := rt.method + " " + name
+					if seen[key] {
+						t.Errorf("%s is in the route table twice", key)
+					}
+					seen[key] = true
+				}
+			}
+			defer func() {
+				if err := recover(); err != nil {
+					t.Fatalf("registering the route table panicked: %v", err)
+				}
+			}()
+			app.router(table)
+		})
+	}
+}
+
+// httprouter refuses a static segment next to a wildcard, which is why the
+// static names go through staticFirst() rather than being registered.
+// Should a router upgrade start allowing it, this test says so
+func TestRouterRefusesStaticNextToWildcard(t *testing.T) {
+	app := newTestApplication(t)
+	defer func() {
+		if recover() == nil {
+			t.Fatal("registering /v1/forums/schema next to /v1/forums/:id did not panic")
+		}
+	}()
+	app.router([]route{
+		{method: http.MethodGet, path: "/v1/forums/:id", handler: app.showForumHandler},
+		{method: http.MethodGet, path: "/v1/forums/schema", handler: app.showForumSchemaHandler},
+	})
+}
+
+// Requests that match no route, or match one under another method, get
+// our own error responses through the full middleware stack. A method
+// we don't serve is answered with a 404 too, but says what was wrong
+func TestRoutesUnmatched(t *testing.T) {
+	app := newTestApplication(t)
+	app.importer = &importer.Importer{}
+	srv := app.routes()
+
+	tests := []struct {
+		method string
+		path   string
+		want   int
+		body   string
+	}{
+		{http.MethodGet, "/v1/nothing", http.StatusNotFound, "could not be found"},
+		{http.MethodGet, "/v1/forums/42/nothing", http.StatusNotFound, "could not be found"},
+		{http.MethodPut, "/v1/forums/42", http.StatusNotFound, "the PUT method is not supported"},
+		{http.MethodPost, "/v1/features", http.StatusNotFound, "the POST method is not supported"},
+		{http.MethodGet, "/v1/features", http.StatusOK, `"faqs":true`},
+		{http.MethodHead, "/v1/features", http.StatusOK, ""},
+		// Imports only run on the schedule until there is an admin check
+		{http.MethodPost, "/v1/admin/imports/run", http.StatusNotFound, "could not be found"},
+		{http.MethodGet, "/v1/admin/jobs/1", http.StatusNotFound, "could not be found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			srv.ServeHTTP(rr, httptest.NewRequest(tt.method, tt.path, nil))
+			if rr.Code != tt.want {
+				t.Errorf("got status %d; want %d", rr.Code, tt.want)
+			}
+			if !strings.Contains(rr.Body.String(), tt.body) {
+				t.Errorf("got body %q; want it to contain %q", rr.Body.String(), tt.body)
+			}
+		})
+	}
+}
+
+// The checkRoute() function sends one request through the router and
+// checks which handler answered it
+func checkRoute(t *testing.T, router *httprouter.Router, method, path, want string) {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(method, path, nil))
+	if got := rr.Body.String(); rr.Code != http.StatusOK || got != want {
+		t.Errorf("%s %s: got status %d from %q; want %q", method, path, rr.Code, got, want)
+	}
+}
+
+// The fillParams() function puts a value in for each wildcard of a route
+// path, so "/v1/forums/:id/faqs/:faq_id" becomes "/v1/forums/1/faqs/1"
+func fillParams(path string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if strings.HasPrefix(s, ":") {
+			segments[i] = "1"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// Doubled slashes are folded before routing, and a trailing slash is
+// redirected away. GET and HEAD get a 301, everything else a 308 so the
+// client repeats the method and body
+func TestNormalizePath(t *testing.T) {
+	srv := newTestApplication(t).routes()
+
+	rr := send(t, srv, http.MethodGet, "//v1//features", "")
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"faqs":true`) {
+		t.Errorf("got status %d from //v1//features; want the feature list", rr.Code)
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		want   int
+		target string
+	}{
+		{http.MethodGet, "/v1/features/", http.StatusMovedPermanently, "/v1/features"},
+		{http.MethodHead, "/v1/features/", http.StatusMovedPermanently, "/v1/features"},
+		{http.MethodGet, "/v1/forums/?page=2&sort=name", http.StatusMovedPermanently, "/v1/forums?page=2&sort=name"},
+		{http.MethodGet, "/v1//forums//", http.StatusMovedPermanently, "/v1/forums"},
+		{http.MethodPost, "/v1/forums/", http.StatusPermanentRedirect, "/v1/forums"},
+		{http.MethodPatch, "/v1/forums/42/", http.StatusPermanentRedirect, "/v1/forums/42"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			before := metricValue("path_redirects")
+			rr := send(t, srv, tt.method, tt.path, "")
+			if rr.Code != tt.want {
+				t.Errorf("got status %d; want %d", rr.Code, tt.want)
+			}
+			if got := rr.Header().Get("Location"); got != tt.target {
+				t.Errorf("got Location %q; want %q", got, tt.target)
+			}
+			if got := metricValue("path_redirects") - before; got != 1 {
+				t.Errorf("path_redirects went up by %d; want 1", got)
+			}
+		})
+	}
+
+	// The root is left alone
+	if rr := send(t, srv, http.MethodGet, "/", ""); rr.Code == http.StatusMovedPermanently {
+		t.Error("got a redirect for /")
+	}
+}
@@ -0,0 +1,53 @@
+// Filename: cmd/api/search.go
+
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"AWD_FinalProject.ryanarmstrong.net/internal/data"
+	"AWD_FinalProject.ryanarmstrong.net/internal/validator"
+)
+
+// searchHandler for the "GET /v1/search" endpoint returns the records
+// matching q, best matches first, each with a link to the full record
+func (app *application) searchHandler(w http.ResponseWriter, r *http.Request) {
+	// Initialize a validator
+	v := validator.New()
+	// Get the URL values map
+	qs := r.URL.Query()
+	q := strings.TrimSpace(app.readString(qs, "q", ""))
+	v.Check(q != "", "q", "required")
+	// Guard the full-text search against pathological terms
+	v.Check(len(q) <= 100, "q", "max_bytes", 100)
+	page := app.readInt(qs, "page", 1, v)
+	pageSize := app.readInt(qs, "page_size", 20, v)
+	v.Check(page > 0, "page", "greater_than_zero")
+	v.Check(page <= 1000, "page", "maximum", 1000)
+	v.Check(pageSize > 0, "page_size", "greater_than_zero")
+	v.Check(pageSize <= 100, "page_size", "maximum", 100)
+	// Check for validation errors
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+	results, metadata, err := app.models.Forums.Search(r.Context(), q, page, pageSize)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrQueryTimeout):
+			app.queryTimeoutResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	for _, result := range results {
+		result.Link = app.urlFor("/v1/forums/%s", result.ID)
+	}
+	err = app.writeCollection(w, r, http.StatusOK, "results", results, metadata, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
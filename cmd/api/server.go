@@ -0,0 +1,81 @@
+// Filename: cmd/api/server.go
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// The serve() method runs the HTTP server and the background tasks. On
+// SIGINT or SIGTERM it stops taking requests, waits for the ones in
+// flight and then for the background tasks to finish their last run
+func (app *application) serve(srv *http.Server) error {
+	// Closing done tells the background tasks to wrap up
+	done := make(chan struct{})
+	if app.config.features.views {
+		app.background(func() {
+			app.runViewFlusher(done)
+		})
+	}
+	if app.config.tables.monitor {
+		app.background(func() {
+			app.runTableMonitor(done)
+		})
+	}
+	if app.importer != nil && app.config.importer.interval > 0 {
+		app.background(func() {
+			app.runImportScheduler(done)
+		})
+	}
+
+	shutdownError := make(chan error)
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+		s := <-quit
+		app.logger.Printf("shutting down server (%s)", s)
+		// Give the requests in flight 20 seconds to complete
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+		err := srv.Shutdown(ctx)
+		close(done)
+		app.wg.Wait()
+		shutdownError <- err
+	}()
+
+	// Start our server
+	app.logger.Printf("Starting %s server on %s", app.config.env, srv.Addr)
+	err := srv.ListenAndServe()
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	if err := <-shutdownError; err != nil {
+		return err
+	}
+	app.logger.Printf("stopped server on %s", srv.Addr)
+	return nil
+}
+
+// The background() method runs fn in a goroutine that shutdown waits for.
+// A panic is logged instead of taking the server down with it
+func (app *application) background(fn func()) {
+	app.wg.Add(1)
+	go func() {
+		defer app.wg.Done()
+		defer func() {
+			if p := recover(); p != nil {
+				err := fmt.Errorf("background task: %v", p)
+				app.logger.Println(err)
+				app.reportError(err, map[string]interface{}{"background": true, "panic": true})
+			}
+		}()
+		fn()
+	}()
+}
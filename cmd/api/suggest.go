@@ -0,0 +1,81 @@
+// Filename: cmd/api/suggest.go
+
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// The furthest a misspelt key may be from a field for us to suggest it
+const maxSuggestDistance = 2
+
+// The unknownFieldError() function builds the message for a key the
+// decoder didn't recognise. When the key is close to one of the JSON
+// fields of dst, the nearest one is suggested
+func unknownFieldError(message string, dst interface{}) error {
+	key := strings.TrimPrefix(message, "json: unknown field ")
+	if suggestion := suggestField(strings.Trim(key, `"`), jsonFields(dst)); suggestion != "" {
+		return fmt.Errorf("body contains unknown key %s, did you mean %q?", key, suggestion)
+	}
+	return fmt.Errorf("body contains unknown key %s", key)
+}
+
+// The jsonFields() function lists the top-level JSON field names of the
+// struct dst points to
+func jsonFields(dst interface{}) []string {
+	t := reflect.TypeOf(dst)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	fields := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+// The suggestField() function returns the field nearest to key, or ""
+// when none is within maxSuggestDistance edits. A key no longer than the
+// distance itself would match almost anything, so short keys need a
+// closer match
+func suggestField(key string, fields []string) string {
+	best, bestDistance := "", maxSuggestDistance+1
+	for _, field := range fields {
+		d := levenshtein(strings.ToLower(key), field)
+		if d < bestDistance && d < len(key) {
+			best, bestDistance = field, d
+		}
+	}
+	return best
+}
+
+// The levenshtein() function counts the single-character insertions,
+// deletions and substitutions that turn a into b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
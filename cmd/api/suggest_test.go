@@ -0,0 +1,87 @@
+// Filename: cmd/api/suggest_test.go
+
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "name", 4},
+		{"name", "", 4},
+		{"name", "name", 0},
+		{"nmae", "name", 2},
+		{"nam", "name", 1},
+		{"names", "name", 1},
+		{"nane", "name", 1},
+		{"kitten", "sitting", 3},
+		// Runes count as one character each
+		{"año", "ano", 1},
+	}
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d; want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSuggestField(t *testing.T) {
+	fields := []string{"name", "level", "contact", "phones", "email", "mode", "languages"}
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"nmae", "name"},
+		{"Name", "name"},
+		{"emial", "email"},
+		{"langauges", "languages"},
+		{"phone", "phones"},
+		{"contcat", "contact"},
+		// The nearest field wins...
+		{"modes", "mode"},
+		// ...and nothing is suggested when every field is too far away
+		{"website", ""},
+		{"studentcapacity", ""},
+		// A key as short as the distance would match almost anything
+		{"id", ""},
+		{"x", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := suggestField(tt.key, fields); got != tt.want {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnknownFieldError(t *testing.T) {
+	var input struct {
+		Name     string `json:"name"`
+		Email    string `json:"email,omitempty"`
+		Internal string `json:"-"`
+		Untagged string
+	}
+	if got, want := jsonFields(&input), []string{"name", "email"}; !slices.Equal(got, want) {
+		t.Errorf("got fields %v; want %v", got, want)
+	}
+
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{`json: unknown field "nmae"`, `body contains unknown key "nmae", did you mean "name"?`},
+		{`json: unknown field "colour"`, `body contains unknown key "colour"`},
+	}
+	for _, tt := range tests {
+		if got := unknownFieldError(tt.message, &input).Error(); got != tt.want {
+			t.Errorf("got %q; want %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// Filename: cmd/api/tables.go
+
+package main
+
+import (
+	"context"
+	"expvar"
+	"time"
+)
+
+// How often the table sizes are checked, and how long a table that stays
+// over the limit goes between warnings
+const (
+	tableCheckInterval = 10 * time.Minute
+	tableAlertInterval = 24 * time.Hour
+)
+
+// tableSizes holds the last size seen of each watched table. It shows up
+// in GET /v1/metrics under "table_bytes"
+var tableSizes = func() *expvar.Map {
+	sizes := new(expvar.Map)
+	metrics.Set("table_bytes", sizes)
+	return sizes
+}()
+
+// The tableMonitor type watches a list of tables for runaway growth. The
+// sizer is the catalog query, kept as a function so it can be swapped
+type tableMonitor struct {
+	sizer   func(ctx context.Context, tables []string) (map[string]int64, error)
+	tables  []string
+	limit   int64
+	alerted map[string]time.Time
+	warn    func(table string, size, limit int64)
+}
+
+// The check() method records the current sizes and warns about each
+// table over the limit, at most once per tableAlertInterval. A table
+// that drops back under the limit can warn again straight away
+func (m *tableMonitor) check(ctx context.Context, now time.Time) error {
+	sizes, err := m.sizer(ctx, m.tables)
+	if err != nil {
+		return err
+	}
+	for table, size := range sizes {
+		value := new(expvar.Int)
+		value.Set(size)
+		tableSizes.Set(table, value)
+		if size < m.limit {
+			delete(m.alerted, table)
+			continue
+		}
+		if last, ok := m.alerted[table]; ok && now.Sub(last) < tableAlertInterval {
+			continue
+		}
+		m.alerted[table] = now
+		m.warn(table, size, m.limit)
+	}
+	return nil
+}
+
+// The runTableMonitor() method checks the table sizes every
+// tableCheckInterval until done is closed
+func (app *application) runTableMonitor(done <-chan struct{}) {
+	monitor := &tableMonitor{
+		sizer:   app.models.Tables.Sizes,
+		tables:  app.config.tables.names,
+		limit:   app.config.tables.limit,
+		alerted: make(map[string]time.Time),
+		warn: func(table string, size, limit int64) {
+			app.logger.Printf("WARNING: table %s is %d bytes, over the %d byte limit", table, size, limit)
+		},
+	}
+	ticker := time.NewTicker(tableCheckInterval)
+	defer ticker.Stop()
+	for {
+		if err := monitor.check(context.Background(), app.clock.Now()); err != nil {
+			app.logger.Printf("checking table sizes: %v", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-done:
+			return
+		}
+	}
+}
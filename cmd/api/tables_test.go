@@ -0,0 +1,78 @@
+// Filename: cmd/api/tables_test.go
+
+package main
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"testing"
+	"time"
+)
+
+// A table over the limit is warned about once a day, and again straight
+// away if it goes back under and over
+func TestTableMonitorCheck(t *testing.T) {
+	sizes := map[string]int64{"forums": 900, "forum_views": 100}
+	warnings := map[string]int{}
+	monitor := &tableMonitor{
+		sizer: func(ctx context.Context, tables []string) (map[string]int64, error) {
+			return sizes, nil
+		},
+		tables:  []string{"forums", "forum_views"},
+		limit:   500,
+		alerted: make(map[string]time.Time),
+		warn: func(table string, size, limit int64) {
+			warnings[table]++
+		},
+	}
+	check := func(now time.Time) {
+		t.Helper()
+		if err := monitor.check(context.Background(), now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	check(start)
+	check(start.Add(tableCheckInterval))
+	if warnings["forums"] != 1 || warnings["forum_views"] != 0 {
+		t.Fatalf("got warnings %v; want forums once", warnings)
+	}
+	if got := tableSizes.Get("forums").(*expvar.Int).Value(); got != 900 {
+		t.Errorf("got table_bytes %d for forums; want 900", got)
+	}
+
+	check(start.Add(tableAlertInterval))
+	if warnings["forums"] != 2 {
+		t.Errorf("got %d warnings a day later; want 2", warnings["forums"])
+	}
+
+	sizes["forums"] = 400
+	check(start.Add(tableAlertInterval + time.Hour))
+	sizes["forums"] = 600
+	check(start.Add(tableAlertInterval + 2*time.Hour))
+	if warnings["forums"] != 3 {
+		t.Errorf("got %d warnings after dropping under; want 3", warnings["forums"])
+	}
+	if got := tableSizes.Get("forums").(*expvar.Int).Value(); got != 600 {
+		t.Errorf("got table_bytes %d for forums; want 600", got)
+	}
+}
+
+// A failed size query is handed back without warning about anything
+func TestTableMonitorCheckError(t *testing.T) {
+	broken := errors.New("connection reset")
+	monitor := &tableMonitor{
+		sizer: func(ctx context.Context, tables []string) (map[string]int64, error) {
+			return nil, broken
+		},
+		alerted: make(map[string]time.Time),
+		warn: func(table string, size, limit int64) {
+			t.Errorf("warned about %s on a failed check", table)
+		},
+	}
+	if err := monitor.check(context.Background(), time.Now()); !errors.Is(err, broken) {
+		t.Errorf("got error %v; want %v", err, broken)
+	}
+}
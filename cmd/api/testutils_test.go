@@ -0,0 +1,166 @@
+// Filename: cmd/api/testutils_test.go
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"AWD_FinalProject.ryanarmstrong.net/internal/cache"
+	"AWD_FinalProject.ryanarmstrong.net/internal/data"
+	"AWD_FinalProject.ryanarmstrong.net/internal/moderation"
+)
+
+// The newTestApplication() function builds an application the way main()
+// does, with the feature flags parsed from args, but without a database.
+// Anything that reaches the models will fail, so tests stick to what can
+// be answered without them
+func newTestApplication(t *testing.T, args ...string) *application {
+	t.Helper()
+	var cfg config
+	cfg.env = "development"
+	fs := flag.NewFlagSet("api", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	cfg.features.registerFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	clock := data.SystemClock{}
+	return &application{
+		config:       cfg,
+		logger:       log.New(io.Discard, "", 0),
+		clock:        clock,
+		cache:        cache.New[int64, data.Forum](10, time.Minute),
+		misses:       cache.New[int64, struct{}](10, missTTL),
+		publicIDs:    cache.New[string, int64](10, publicIDTTL),
+		publicMisses: cache.New[string, struct{}](10, missTTL),
+		views:        newViewCounter(clock),
+		moderation:   moderation.New(),
+		reporter:     nopReporter{},
+	}
+}
+
+// The handlerName() function gives the name of a handler method, so
+// "showForumHandler" for app.showForumHandler
+func handlerName(h http.HandlerFunc) string {
+	name := runtime.FuncForPC(reflect.ValueOf(h).Pointer()).Name()
+	name = strings.TrimSuffix(name, "-fm")
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// The stubRoutes() function swaps every handler in a route table, static
+// ones included, for one that answers with the name of the handler it
+// replaced. That lets a test see where the router sent a request without
+// running the real handler
+func stubRoutes(table []route) []route {
+	stub := func(h http.HandlerFunc) http.HandlerFunc {
+		name := handlerName(h)
+		return func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, name)
+		}
+	}
+	stubbed := make([]route, len(table))
+	for i, rt := range table {
+		rt.handler = stub(rt.handler)
+		if rt.static != nil {
+			static := make(map[string]http.HandlerFunc, len(rt.static))
+			for name, h := range rt.static {
+				static[name] = stub(h)
+			}
+			rt.static = static
+		}
+		stubbed[i] = rt
+	}
+	return stubbed
+}
+
+// The send() function serves one request and returns the recorded
+// response. A body is sent as JSON
+func send(t *testing.T, h http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, r)
+	return rr
+}
+
+// The errorFields() function decodes the field errors of a 422 response
+func errorFields(t *testing.T, rr *httptest.ResponseRecorder) map[string]string {
+	t.Helper()
+	var body struct {
+		Error map[string]string `json:"error"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decoding %q: %v", rr.Body.String(), err)
+	}
+	return body.Error
+}
+
+// A forumOption changes one thing about a forum built by newTestForum()
+type forumOption func(*data.Forum)
+
+// The newTestForum() function builds a forum that passes ValidateForum(),
+// then applies opts in order. Tests name only what they care about
+func newTestForum(opts ...forumOption) data.Forum {
+	capacity := int32(30)
+	forum := data.Forum{
+		Name:            "Study Group",
+		Level:           "University",
+		Contact:         "Ana Pech",
+		Phones:          data.Phones{Primary: "501-600-1234", Extension: "12"},
+		Email:           "study@example.com",
+		Website:         "https://study.example.com",
+		Address:         "12 Albert Street",
+		Mode:            data.Modes{"online"},
+		Languages:       data.Languages{"en"},
+		StudentCapacity: &capacity,
+		Version:         1,
+	}
+	for _, opt := range opts {
+		opt(&forum)
+	}
+	return forum
+}
+
+// The withID() option gives the forum its serial and public ids
+func withID(id int64, publicID string) forumOption {
+	return func(forum *data.Forum) {
+		forum.ID, forum.PublicID = id, publicID
+	}
+}
+
+// The withName() option renames the forum
+func withName(name string) forumOption {
+	return func(forum *data.Forum) {
+		forum.Name = name
+	}
+}
+
+// The withPublicContact() option shows the forum's phone and email to
+// everyone
+func withPublicContact() forumOption {
+	return func(forum *data.Forum) {
+		forum.PublicContact = true
+	}
+}
+
+// The withEnrollment() option sets the current enrollment, hidden from
+// the public when private is set
+func withEnrollment(enrollment int32, private bool) forumOption {
+	return func(forum *data.Forum) {
+		forum.CurrentEnrollment = &enrollment
+		forum.EnrollmentPrivate = private
+	}
+}
@@ -0,0 +1,98 @@
+// Filename: cmd/api/tracing_test.go
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// The recordSpans() function installs a tracer provider that keeps the
+// spans in memory, and puts the previous one back when the test is done
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagator)
+	})
+	return recorder
+}
+
+// Each request gets a server span named by its route pattern, carrying
+// the status, and continuing the caller's trace
+func TestTraceRequests(t *testing.T) {
+	recorder := recordSpans(t)
+	app := newTestApplication(t)
+	app.config.tracing.endpoint = "http://collector.test"
+	router := app.router([]route{
+		{method: http.MethodGet, path: "/v1/ok/:id", handler: func(w http.ResponseWriter, r *http.Request) {}},
+		{method: http.MethodGet, path: "/v1/broken", handler: func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}},
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	r := httptest.NewRequest(http.MethodGet, "/v1/ok/42", nil)
+	r.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), r)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/broken", nil))
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans; want 2", len(spans))
+	}
+	ok, broken := spans[0], spans[1]
+	if ok.Name() != "GET /v1/ok/:id" {
+		t.Errorf("got span name %q; want the route pattern", ok.Name())
+	}
+	if got := ok.SpanContext().TraceID().String(); got != traceID {
+		t.Errorf("got trace id %s; want the caller's %s", got, traceID)
+	}
+	if got := spanAttribute(ok, "http.route"); got.AsString() != "/v1/ok/:id" {
+		t.Errorf("got http.route %q; want /v1/ok/:id", got.AsString())
+	}
+	if got := spanAttribute(ok, "http.response.status_code"); got.AsInt64() != http.StatusOK {
+		t.Errorf("got status attribute %d; want %d", got.AsInt64(), http.StatusOK)
+	}
+	if ok.Status().Code == codes.Error {
+		t.Error("a 200 was marked as an error")
+	}
+	if broken.Status().Code != codes.Error {
+		t.Error("a 500 was not marked as an error")
+	}
+}
+
+// With no endpoint the handlers run without spans
+func TestTraceDisabled(t *testing.T) {
+	recorder := recordSpans(t)
+	app := newTestApplication(t)
+	router := app.router([]route{
+		{method: http.MethodGet, path: "/v1/ok", handler: func(w http.ResponseWriter, r *http.Request) {}},
+	})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/ok", nil))
+	if got := len(recorder.Ended()); got != 0 {
+		t.Errorf("got %d spans with tracing off; want 0", got)
+	}
+}
+
+// The spanAttribute() function finds an attribute of a span by key
+func spanAttribute(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
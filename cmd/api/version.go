@@ -0,0 +1,72 @@
+// Filename: cmd/api/version.go
+
+package main
+
+import (
+	"net/http"
+	"runtime/debug"
+)
+
+// The version and build time are injected at build time, e.g
+// go build -ldflags "-X main.version=1.1.0 -X main.buildTime=2022-05-01T10:00:00Z" ./cmd/api
+var (
+	version   = "1.0.0"
+	buildTime = ""
+)
+
+// The buildInfo type describes exactly what binary is running
+type buildInfo struct {
+	Version   string `json:"version"`
+	Revision  string `json:"revision,omitempty"`
+	Dirty     bool   `json:"dirty"`
+	BuildTime string `json:"build_time,omitempty"`
+	GoVersion string `json:"go_version,omitempty"`
+}
+
+// build is read once at startup
+var build = readBuildInfo()
+
+// The readBuildInfo() function combines the -ldflags values with the VCS
+// details the Go toolchain embeds. Under "go run" there is no VCS data,
+// so only the version is guaranteed to be set
+func readBuildInfo() buildInfo {
+	info := buildInfo{Version: version, BuildTime: buildTime}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	info.GoVersion = bi.GoVersion
+	for _, setting := range bi.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			info.Revision = setting.Value
+		case "vcs.modified":
+			info.Dirty = setting.Value == "true"
+		case "vcs.time":
+			// Fall back to the commit time when no build time was injected
+			if info.BuildTime == "" {
+				info.BuildTime = setting.Value
+			}
+		}
+	}
+	return info
+}
+
+// The versionHandler for the "GET /v1/version" endpoint
+func (app *application) versionHandler(w http.ResponseWriter, r *http.Request) {
+	// The build never changes while the process runs
+	headers := make(http.Header)
+	headers.Set("Cache-Control", "public, max-age=300")
+	err := app.writeResource(w, r, http.StatusOK, "build", build, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The apiVersion() middleware adds the X-Api-Version header to every response
+func (app *application) apiVersion(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Api-Version", build.Version)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,93 @@
+// Filename: cmd/api/views.go
+
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"AWD_FinalProject.ryanarmstrong.net/internal/data"
+)
+
+// How often the buffered view counts are written to the database
+const viewFlushInterval = 30 * time.Second
+
+// The viewKey type identifies one forum on one UTC day
+type viewKey struct {
+	forumID int64
+	day     time.Time
+}
+
+// The viewCounter type buffers forum views in memory so that reading a
+// forum never has to write to the database
+type viewCounter struct {
+	mu     sync.Mutex
+	clock  data.Clock
+	counts map[viewKey]int64
+}
+
+func newViewCounter(clock data.Clock) *viewCounter {
+	return &viewCounter{clock: clock, counts: make(map[viewKey]int64)}
+}
+
+// The add() method records a single view of the forum
+func (c *viewCounter) add(forumID int64) {
+	day := c.clock.Now().UTC().Truncate(24 * time.Hour)
+	c.mu.Lock()
+	c.counts[viewKey{forumID: forumID, day: day}]++
+	c.mu.Unlock()
+}
+
+// The take() method hands over the buffered counts grouped by day and
+// starts a fresh buffer
+func (c *viewCounter) take() map[time.Time]map[int64]int64 {
+	c.mu.Lock()
+	counts := c.counts
+	c.counts = make(map[viewKey]int64)
+	c.mu.Unlock()
+	days := make(map[time.Time]map[int64]int64)
+	for key, count := range counts {
+		if days[key.day] == nil {
+			days[key.day] = make(map[int64]int64)
+		}
+		days[key.day][key.forumID] = count
+	}
+	return days
+}
+
+// The putBack() method returns counts that could not be written so the
+// next flush tries them again
+func (c *viewCounter) putBack(day time.Time, counts map[int64]int64) {
+	c.mu.Lock()
+	for forumID, count := range counts {
+		c.counts[viewKey{forumID: forumID, day: day}] += count
+	}
+	c.mu.Unlock()
+}
+
+// The flushViews() method writes the buffered view counts to the database
+func (app *application) flushViews(ctx context.Context) {
+	for day, counts := range app.views.take() {
+		if err := app.models.Views.Add(ctx, day, counts); err != nil {
+			app.logger.Printf("flushing forum views: %v", err)
+			app.views.putBack(day, counts)
+		}
+	}
+}
+
+// The runViewFlusher() method flushes the view counts every
+// viewFlushInterval and one last time once done is closed
+func (app *application) runViewFlusher(done <-chan struct{}) {
+	ticker := time.NewTicker(viewFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			app.flushViews(context.Background())
+		case <-done:
+			app.flushViews(context.Background())
+			return
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Filename: cmd/api/views_test.go
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// A testClock is a clock the test moves by hand
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+// Views are counted against the UTC day the clock is on, so a view late
+// in the evening in Belize belongs to the next day
+func TestViewCounterDays(t *testing.T) {
+	belize := time.FixedZone("CST", -6*60*60)
+	clock := &testClock{now: time.Date(2026, 1, 15, 17, 0, 0, 0, belize)}
+	views := newViewCounter(clock)
+
+	views.add(1)
+	views.add(1)
+	views.add(2)
+	clock.now = clock.now.Add(2 * time.Hour)
+	views.add(1)
+
+	days := views.take()
+	jan15 := time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)
+	jan16 := time.Date(2026, 1, 16, 0, 0, 0, 0, time.UTC)
+	if len(days) != 2 {
+		t.Fatalf("got %d days; want 2: %v", len(days), days)
+	}
+	if got := days[jan15]; got[1] != 2 || got[2] != 1 {
+		t.Errorf("got %v on Jan 15; want forum 1 twice and forum 2 once", got)
+	}
+	if got := days[jan16]; got[1] != 1 || len(got) != 1 {
+		t.Errorf("got %v on Jan 16; want forum 1 once", got)
+	}
+	if len(views.take()) != 0 {
+		t.Error("take() left the counts behind")
+	}
+}
+
+// Counts that couldn't be written go back into the buffer and add up
+// with the views since
+func TestViewCounterPutBack(t *testing.T) {
+	clock := &testClock{now: time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC)}
+	views := newViewCounter(clock)
+	views.add(1)
+	days := views.take()
+	views.add(1)
+	for day, counts := range days {
+		views.putBack(day, counts)
+	}
+	if got := views.take()[time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)][1]; got != 2 {
+		t.Errorf("got %d views; want 2", got)
+	}
+}
@@ -0,0 +1,74 @@
+// Filename: cmd/api/writer.go
+
+package main
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// The responseWriter type wraps the http.ResponseWriter of every request
+// to remember whether the response has started and with which status. The
+// write helpers use it to refuse a second response and the tracing
+// middleware reads the status from it
+type responseWriter struct {
+	http.ResponseWriter
+	status  int
+	started bool
+}
+
+func (rw *responseWriter) WriteHeader(status int) {
+	// A second status line can't be sent, so don't let it reach net/http
+	// and log a superfluous WriteHeader call
+	if rw.started {
+		return
+	}
+	rw.status = status
+	rw.started = true
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	if !rw.started {
+		rw.status = http.StatusOK
+		rw.started = true
+	}
+	return rw.ResponseWriter.Write(b)
+}
+
+// Unwrap() lets http.ResponseController reach the original writer
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// The trackResponse() middleware wraps the writer of every request
+func (app *application) trackResponse(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&responseWriter{ResponseWriter: w, status: http.StatusOK}, r)
+	})
+}
+
+// The writeBody() function sends a complete response body. The length is
+// always set, and a HEAD request gets the same headers as a GET with the
+// body left off, so the body is only ever built once
+func writeBody(w http.ResponseWriter, r *http.Request, status int, body ...[]byte) {
+	length := 0
+	for _, b := range body {
+		length += len(b)
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(length))
+	w.WriteHeader(status)
+	if r.Method == http.MethodHead {
+		return
+	}
+	for _, b := range body {
+		w.Write(b)
+	}
+}
+
+// The responseStarted() function reports whether a response has already
+// been sent on w
+func responseStarted(w http.ResponseWriter) bool {
+	rw, ok := w.(*responseWriter)
+	return ok && rw.started
+}
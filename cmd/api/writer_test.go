@@ -0,0 +1,77 @@
+// Filename: cmd/api/writer_test.go
+
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// A handler that sends an error and then carries on, the way
+// createForumHandler() used to after a failed insert, must not get its
+// second response out
+func TestSecondResponseDropped(t *testing.T) {
+	app := newTestApplication(t)
+	handler := app.trackResponse(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		app.serverErrorResponse(w, r, errors.New("insert failed"))
+		app.writeResource(w, r, http.StatusCreated, "forum", map[string]string{"name": "Study Group"}, nil)
+	}))
+
+	before := metricValue("duplicate_writes")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/v1/forums", nil))
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("got status %d; want %d", rr.Code, http.StatusInternalServerError)
+	}
+	want := "{\"error\":\"" + app.translator(httptest.NewRequest(http.MethodGet, "/", nil)).T("server_error") + "\"}\n"
+	if rr.Body.String() != want {
+		t.Errorf("got body %q; want only the error %q", rr.Body.String(), want)
+	}
+	if got := metricValue("duplicate_writes") - before; got != 1 {
+		t.Errorf("duplicate_writes went up by %d; want 1", got)
+	}
+}
+
+// Only the first status line reaches the client
+func TestResponseWriterStatus(t *testing.T) {
+	rr := httptest.NewRecorder()
+	rw := &responseWriter{ResponseWriter: rr, status: http.StatusOK}
+	if responseStarted(rw) {
+		t.Fatal("a fresh writer has started")
+	}
+	rw.WriteHeader(http.StatusAccepted)
+	rw.WriteHeader(http.StatusInternalServerError)
+	if rw.status != http.StatusAccepted || rr.Code != http.StatusAccepted {
+		t.Errorf("got status %d, sent %d; want %d", rw.status, rr.Code, http.StatusAccepted)
+	}
+	if !responseStarted(rw) {
+		t.Error("the writer hasn't started after WriteHeader()")
+	}
+
+	// A body with no status line is a 200
+	rw = &responseWriter{ResponseWriter: httptest.NewRecorder()}
+	rw.Write([]byte("ok"))
+	if rw.status != http.StatusOK || !rw.started {
+		t.Errorf("got status %d, started %t; want 200, true", rw.status, rw.started)
+	}
+}
+
+// A HEAD response has the headers of the GET, length included, but no body
+func TestWriteBodyHead(t *testing.T) {
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		rr := httptest.NewRecorder()
+		writeBody(rr, httptest.NewRequest(method, "/", nil), http.StatusOK, []byte(`{"a":`), []byte(`1}`))
+		if got := rr.Header().Get("Content-Length"); got != "7" {
+			t.Errorf("%s: got Content-Length %q; want 7", method, got)
+		}
+		want := `{"a":1}`
+		if method == http.MethodHead {
+			want = ""
+		}
+		if rr.Body.String() != want {
+			t.Errorf("%s: got body %q; want %q", method, rr.Body.String(), want)
+		}
+	}
+}
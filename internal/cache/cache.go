@@ -0,0 +1,115 @@
+// Filename: internal/cache/cache.go
+
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// The entry type holds a cached value and the time it stops being fresh
+type entry[K comparable, V any] struct {
+	key       K
+	value     V
+	expiresAt time.Time
+}
+
+// Cache is an in-process LRU cache capped by entry count where every
+// entry expires after a fixed TTL. It is safe for concurrent use
+type Cache[K comparable, V any] struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	order    *list.List
+	items    map[K]*list.Element
+	stamp    uint64
+}
+
+// New() creates a new Cache holding at most capacity entries
+func New[K comparable, V any](capacity int, ttl time.Duration) *Cache[K, V] {
+	return &Cache[K, V]{
+		capacity: capacity,
+		ttl:      ttl,
+		order:    list.New(),
+		items:    make(map[K]*list.Element),
+	}
+}
+
+// Get() returns the value stored for key if it is present and fresh
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	element, ok := c.items[key]
+	if !ok {
+		return zero, false
+	}
+	e := element.Value.(*entry[K, V])
+	// Drop the entry if it has gone stale
+	if time.Now().After(e.expiresAt) {
+		c.remove(element)
+		return zero, false
+	}
+	c.order.MoveToFront(element)
+	return e.value, true
+}
+
+// Stamp() returns the current invalidation stamp. Read it before loading
+// a value from the source and pass it to Set(), so that a value loaded
+// before an invalidation can never be cached after it
+func (c *Cache[K, V]) Stamp() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stamp
+}
+
+// Set() stores the value for key unless an invalidation happened since
+// the stamp was taken. It reports whether the value was stored
+func (c *Cache[K, V]) Set(key K, value V, stamp uint64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.capacity <= 0 || stamp != c.stamp {
+		return false
+	}
+	expiresAt := time.Now().Add(c.ttl)
+	if element, ok := c.items[key]; ok {
+		e := element.Value.(*entry[K, V])
+		e.value = value
+		e.expiresAt = expiresAt
+		c.order.MoveToFront(element)
+		return true
+	}
+	c.items[key] = c.order.PushFront(&entry[K, V]{key: key, value: value, expiresAt: expiresAt})
+	// Evict the least recently used entries once we go over capacity
+	for c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+	}
+	return true
+}
+
+// Invalidate() removes the entry for key
+func (c *Cache[K, V]) Invalidate(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stamp++
+	if element, ok := c.items[key]; ok {
+		c.remove(element)
+	}
+}
+
+// Len() returns the number of entries currently held, fresh or not
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// remove() unlinks an element; the caller must hold the lock
+func (c *Cache[K, V]) remove(element *list.Element) {
+	e := c.order.Remove(element).(*entry[K, V])
+	delete(c.items, e.key)
+}
@@ -0,0 +1,135 @@
+// Filename: internal/cache/cache_test.go
+
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetSet(t *testing.T) {
+	c := New[int, string](2, time.Minute)
+	if _, ok := c.Get(1); ok {
+		t.Fatal("got a value from an empty cache")
+	}
+	if !c.Set(1, "one", c.Stamp()) {
+		t.Fatal("Set() refused a fresh stamp")
+	}
+	if got, ok := c.Get(1); !ok || got != "one" {
+		t.Errorf("got %q, %t; want \"one\", true", got, ok)
+	}
+	// Setting a key again replaces its value
+	c.Set(1, "uno", c.Stamp())
+	if got, _ := c.Get(1); got != "uno" {
+		t.Errorf("got %q after replacing; want \"uno\"", got)
+	}
+	if c.Len() != 1 {
+		t.Errorf("got %d entries; want 1", c.Len())
+	}
+}
+
+// Going over capacity evicts the least recently used entry, and a Get()
+// counts as a use
+func TestEviction(t *testing.T) {
+	c := New[int, string](2, time.Minute)
+	c.Set(1, "one", c.Stamp())
+	c.Set(2, "two", c.Stamp())
+	c.Get(1)
+	c.Set(3, "three", c.Stamp())
+
+	if _, ok := c.Get(2); ok {
+		t.Error("the least recently used entry was kept")
+	}
+	for _, key := range []int{1, 3} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("entry %d was evicted", key)
+		}
+	}
+	if c.Len() != 2 {
+		t.Errorf("got %d entries; want 2", c.Len())
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	c := New[int, string](2, 10*time.Millisecond)
+	c.Set(1, "one", c.Stamp())
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := c.Get(1); ok {
+		t.Error("got a stale entry")
+	}
+	if c.Len() != 0 {
+		t.Errorf("the stale entry was kept, got %d entries", c.Len())
+	}
+}
+
+// A capacity of zero switches the cache off
+func TestZeroCapacity(t *testing.T) {
+	c := New[int, string](0, time.Minute)
+	if c.Set(1, "one", c.Stamp()) {
+		t.Error("Set() stored a value with no capacity")
+	}
+	if _, ok := c.Get(1); ok {
+		t.Error("got a value with no capacity")
+	}
+}
+
+// A value loaded before an invalidation must not be cached after it,
+// even if its Set() comes last
+func TestStaleStamp(t *testing.T) {
+	c := New[int, string](2, time.Minute)
+	stamp := c.Stamp()
+	// A write lands between the read of the old value and its Set()
+	c.Invalidate(1)
+	if c.Set(1, "old", stamp) {
+		t.Error("Set() stored a value loaded before the invalidation")
+	}
+	if _, ok := c.Get(1); ok {
+		t.Error("got the value loaded before the invalidation")
+	}
+	// A load that starts after the invalidation is cached as usual
+	if !c.Set(1, "new", c.Stamp()) {
+		t.Error("Set() refused a value loaded after the invalidation")
+	}
+}
+
+// Readers load a value and cache it while a writer keeps changing the
+// source and invalidating. Whatever interleaving happens, once the writer
+// is done the cache may only hold the last value written
+func TestInvalidateRace(t *testing.T) {
+	c := New[int, int64](10, time.Minute)
+	var source atomic.Int64
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if _, ok := c.Get(1); ok {
+					continue
+				}
+				stamp := c.Stamp()
+				value := source.Load()
+				c.Set(1, value, stamp)
+			}
+		}()
+	}
+	for i := 0; i < 1000; i++ {
+		source.Add(1)
+		c.Invalidate(1)
+	}
+	close(done)
+	wg.Wait()
+
+	if got, ok := c.Get(1); ok && got != source.Load() {
+		t.Errorf("cache holds %d after the last write of %d", got, source.Load())
+	}
+}
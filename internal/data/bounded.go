@@ -0,0 +1,92 @@
+// Filename: internal/data/bounded.go
+
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// MaxInputEntries is the most elements any array in a request body may
+// hold. Decoding stops as soon as it is passed, so a huge array costs us
+// nothing beyond reading it. It sits well above any validation limit so
+// normal bodies still get the usual keyed validation errors
+var MaxInputEntries = 50
+
+// A TooManyEntriesError is returned when an input array passes
+// MaxInputEntries
+type TooManyEntriesError struct {
+	Field string
+	Limit int
+}
+
+func (e *TooManyEntriesError) Error() string {
+	return fmt.Sprintf("%s must not contain more than %d entries", e.Field, e.Limit)
+}
+
+// The boundedStrings() function decodes a JSON array of strings for the
+// named field, giving up once it holds more than MaxInputEntries. ok is
+// false when the value isn't an array of strings at all
+func boundedStrings(js []byte, field string) (values []string, ok bool, err error) {
+	dec := json.NewDecoder(bytes.NewReader(js))
+	if token, err := dec.Token(); err != nil || token != json.Delim('[') {
+		return nil, false, nil
+	}
+	values = []string{}
+	for dec.More() {
+		if len(values) == MaxInputEntries {
+			return nil, true, &TooManyEntriesError{Field: field, Limit: MaxInputEntries}
+		}
+		token, err := dec.Token()
+		if err != nil {
+			return nil, false, nil
+		}
+		value, isString := token.(string)
+		if !isString {
+			return nil, false, nil
+		}
+		values = append(values, value)
+	}
+	return values, true, nil
+}
+
+// IDList holds a list of ids from a request body, such as the new order
+// of a forum's FAQs. Like the string lists it stops decoding once it
+// holds more than MaxInputEntries
+type IDList []int64
+
+func (l *IDList) UnmarshalJSON(js []byte) error {
+	if string(js) == "null" {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(js))
+	dec.UseNumber()
+	notIDs := errors.New("ids must be an array of integers")
+	if token, err := dec.Token(); err != nil || token != json.Delim('[') {
+		return notIDs
+	}
+	ids := IDList{}
+	for dec.More() {
+		if len(ids) == MaxInputEntries {
+			return &TooManyEntriesError{Field: "ids", Limit: MaxInputEntries}
+		}
+		token, err := dec.Token()
+		if err != nil {
+			return notIDs
+		}
+		number, isNumber := token.(json.Number)
+		if !isNumber {
+			return notIDs
+		}
+		id, err := strconv.ParseInt(number.String(), 10, 64)
+		if err != nil {
+			return notIDs
+		}
+		ids = append(ids, id)
+	}
+	*l = ids
+	return nil
+}
@@ -0,0 +1,102 @@
+// Filename: internal/data/bounded_test.go
+
+package data
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// The listOf() function builds a JSON array of n copies of item, e.g.
+// ["a","a"] from `"a"`
+func listOf(n int, item string) string {
+	items := make([]string, n)
+	for i := range items {
+		items[i] = item
+	}
+	return "[" + strings.Join(items, ",") + "]"
+}
+
+// A list may hold MaxInputEntries entries and no more, whichever form it
+// comes in
+func TestDecodeStringListBounds(t *testing.T) {
+	tests := []struct {
+		name    string
+		js      string
+		tooMany bool
+	}{
+		{"array at the limit", listOf(MaxInputEntries, `"a"`), false},
+		{"array past the limit", listOf(MaxInputEntries+1, `"a"`), true},
+		{"string at the limit", `"` + strings.Repeat("a,", MaxInputEntries-1) + `a"`, false},
+		{"string past the limit", `"` + strings.Repeat("a,", MaxInputEntries) + `a"`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := decodeStringList([]byte(tt.js), "mode")
+			var tooMany *TooManyEntriesError
+			if tt.tooMany {
+				if !errors.As(err, &tooMany) || tooMany.Field != "mode" || tooMany.Limit != MaxInputEntries {
+					t.Fatalf("got %v; want a TooManyEntriesError for mode", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(list) != MaxInputEntries {
+				t.Errorf("got %d entries; want %d", len(list), MaxInputEntries)
+			}
+		})
+	}
+}
+
+// An array past the limit is given up on without reading the rest of
+// it, so what follows the limit doesn't matter
+func TestBoundedStringsStopsEarly(t *testing.T) {
+	js := listOf(MaxInputEntries+1, `"a"`)
+	js = js[:len(js)-1] + `, not even JSON`
+	_, ok, err := boundedStrings([]byte(js), "mode")
+	var tooMany *TooManyEntriesError
+	if !ok || !errors.As(err, &tooMany) {
+		t.Errorf("got ok %t, error %v; want a TooManyEntriesError", ok, err)
+	}
+}
+
+func TestIDList(t *testing.T) {
+	tests := []struct {
+		name string
+		js   string
+		want IDList
+		err  string
+	}{
+		{"ids", `[3, 1, 2]`, IDList{3, 1, 2}, ""},
+		{"empty", `[]`, IDList{}, ""},
+		{"at the limit", listOf(MaxInputEntries, "1"), nil, ""},
+		{"past the limit", listOf(MaxInputEntries+1, "1"), nil, "ids must not contain more than 50 entries"},
+		{"strings", `["1", "2"]`, nil, "ids must be an array of integers"},
+		{"fractions", `[1.5]`, nil, "ids must be an array of integers"},
+		{"too large", `[9223372036854775808]`, nil, "ids must be an array of integers"},
+		{"not an array", `{"ids": [1]}`, nil, "ids must be an array of integers"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ids IDList
+			err := json.Unmarshal([]byte(tt.js), &ids)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("got error %v; want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want != nil && !slices.Equal(ids, tt.want) {
+				t.Errorf("got %v; want %v", ids, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+// Filename: internal/data/changes.go
+
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// The kinds of change recorded in the changes feed
+const (
+	ChangeCreated = "created"
+	ChangeUpdated = "updated"
+	ChangeDeleted = "deleted"
+)
+
+// ChangeSettleWindow is how old a change must be before the feed serves
+// it. Concurrent transactions can commit their changes out of id order,
+// but none runs longer than our 3 second query timeout, so every change
+// older than this window is already visible. Paging by id over settled
+// changes therefore never skips a late commit
+const ChangeSettleWindow = 5 * time.Second
+
+// A Change is one entry in the forums changes feed. Forum holds the
+// current representation, or nil once the forum has been deleted or
+// while the public listing hides it for having expired or been archived.
+// The
+// forum is named by its public id, which is only missing for forums
+// deleted before the feed recorded it
+type Change struct {
+	Cursor    int64     `json:"cursor,string"`
+	ForumID   int64     `json:"-"`
+	PublicID  *string   `json:"public_id"`
+	Type      string    `json:"change_type"`
+	ChangedAt Timestamp `json:"changed_at"`
+	Forum     *Forum    `json:"forum"`
+}
+
+// Define a ChangeModel which wraps a sql.DB connection pool. Clock
+// decides which forums have expired, as it does for ForumModel
+type ChangeModel struct {
+	DB    *sql.DB
+	Clock Clock
+}
+
+// The recordChange() function writes a change to the feed as part of the
+// transaction that makes it
+func recordChange(ctx context.Context, tx *sql.Tx, forumID int64, publicID string, changeType string) error {
+	query := `
+		INSERT INTO forum_changes (forum_id, public_id, change_type)
+		VALUES ($1, $2, $3)
+	`
+	_, err := tx.ExecContext(ctx, query, forumID, publicID, changeType)
+	return err
+}
+
+// The settledBefore() method returns the time a change must have been
+// recorded before to have settled, as of the model's clock
+func (m ChangeModel) settledBefore() time.Time {
+	return m.now().Add(-ChangeSettleWindow)
+}
+
+// GetSince() returns up to limit settled changes recorded after the cursor
+// and, when since is not zero, after that time. Changes come back in the
+// order they were recorded along with the current state of each forum
+func (m ChangeModel) GetSince(ctx context.Context, cursor int64, since time.Time, limit int) ([]*Change, error) {
+	ctx, span := startQuery(ctx, "ChangeModel.GetSince")
+	query := `
+		SELECT id, forum_id, public_id, change_type, changed_at
+		FROM forum_changes
+		WHERE id > $1
+		AND ($2::timestamptz IS NULL OR changed_at > $2)
+		AND changed_at < $3
+		ORDER BY id ASC
+		LIMIT $4
+	`
+	// Create a context
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+	// A zero since means no time filter
+	sinceArg := sql.NullTime{Time: since, Valid: !since.IsZero()}
+	settled := m.settledBefore()
+	span.statement(query, cursor, sinceArg, settled, limit)
+	rows, err := m.DB.QueryContext(ctx, query, cursor, sinceArg, settled, limit)
+	if err != nil {
+		endQuery(span, 0, err)
+		return nil, err
+	}
+	defer rows.Close()
+	changes := []*Change{}
+	ids := []int64{}
+	for rows.Next() {
+		var change Change
+		err := rows.Scan(&change.Cursor, &change.ForumID, &change.PublicID, &change.Type, &change.ChangedAt)
+		if err != nil {
+			endQuery(span, len(changes), err)
+			return nil, err
+		}
+		changes = append(changes, &change)
+		ids = append(ids, change.ForumID)
+	}
+	if err = rows.Err(); err != nil {
+		endQuery(span, len(changes), err)
+		return nil, err
+	}
+	// Attach the current representation of every forum that still exists
+	forums, err := m.currentForums(ctx, ids)
+	if err != nil {
+		endQuery(span, len(changes), err)
+		return nil, err
+	}
+	for _, change := range changes {
+		change.Forum = forums[change.ForumID]
+	}
+	endQuery(span, len(changes), nil)
+	return changes, nil
+}
+
+// The currentForums() method fetches the forums with the given ids that
+// still exist, keyed by id. Only the forums the public listing shows are
+// fetched, so the feed can't be used to read the hidden ones
+func (m ChangeModel) currentForums(ctx context.Context, ids []int64) (map[int64]*Forum, error) {
+	forums := make(map[int64]*Forum)
+	if len(ids) == 0 {
+		return forums, nil
+	}
+	query, args := currentForumsQuery(ids, m.now())
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		forum, err := scanForum(rows)
+		if err != nil {
+			return nil, err
+		}
+		forums[forum.ID] = forum
+	}
+	return forums, rows.Err()
+}
+
+// The currentForumsQuery() function builds the query of currentForums(),
+// with the listing's own filter for what the public may see
+func currentForumsQuery(ids []int64, now time.Time) (string, []interface{}) {
+	b := &queryBuilder{}
+	b.where("id = ANY(?)", ids)
+	ForumFilter{}.apply(b, now, "")
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM forums
+		%s`, forumColumns, b.whereClause())
+	return query, b.args
+}
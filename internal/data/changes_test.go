@@ -0,0 +1,40 @@
+// Filename: internal/data/changes_test.go
+
+package data
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The feed attaches only the forums the public listing would show, as of
+// the model's clock
+func TestCurrentForumsQuery(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	query, args := currentForumsQuery([]int64{4, 9}, now)
+	want := "WHERE id = ANY($1)\n\t\tAND expires_at > $2\n\t\tAND archived_at IS NULL"
+	if !strings.Contains(query, want) {
+		t.Errorf("got query %q; want it to contain %q", query, want)
+	}
+	if wantArgs := []interface{}{[]int64{4, 9}, now}; !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("got args %v; want %v", args, wantArgs)
+	}
+}
+
+func TestChangeModelNow(t *testing.T) {
+	later := time.Date(2027, 6, 1, 0, 0, 0, 0, time.UTC)
+	if got := (ChangeModel{Clock: fixedClock(later)}).now(); !got.Equal(later) {
+		t.Errorf("got %v; want the model clock's %v", got, later)
+	}
+}
+
+// A change settles once the window has passed on the model's clock
+func TestChangeModelSettledBefore(t *testing.T) {
+	now := time.Date(2027, 6, 1, 12, 0, 0, 0, time.UTC)
+	want := time.Date(2027, 6, 1, 11, 59, 55, 0, time.UTC)
+	if got := (ChangeModel{Clock: fixedClock(now)}).settledBefore(); !got.Equal(want) {
+		t.Errorf("got %v; want %v", got, want)
+	}
+}
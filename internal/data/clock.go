@@ -0,0 +1,48 @@
+// Filename: internal/data/clock.go
+
+package data
+
+import "time"
+
+// A Clock tells the time. Expiry is worked out from the clock rather than
+// from NOW() in SQL, so a different clock can be swapped in to see how
+// listings lapse without waiting a year
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the real clock
+type SystemClock struct{}
+
+// Now() returns the current time
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// The now() method reads the model's clock, the real one when none is set
+func (m ForumModel) now() time.Time {
+	return clockNow(m.Clock)
+}
+
+// The now() method reads the model's clock, the real one when none is set
+func (m ChangeModel) now() time.Time {
+	return clockNow(m.Clock)
+}
+
+// The now() method reads the model's clock, the real one when none is set
+func (m ImportModel) now() time.Time {
+	return clockNow(m.Clock)
+}
+
+// The now() method reads the model's clock, the real one when none is set
+func (m JobModel) now() time.Time {
+	return clockNow(m.Clock)
+}
+
+// The clockNow() function reads clock, or the real time when it is nil
+func clockNow(clock Clock) time.Time {
+	if clock == nil {
+		return time.Now()
+	}
+	return clock.Now()
+}
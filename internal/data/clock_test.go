@@ -0,0 +1,54 @@
+// Filename: internal/data/clock_test.go
+
+package data
+
+import (
+	"testing"
+	"time"
+)
+
+// A fixedClock always tells the same time
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time {
+	return time.Time(c)
+}
+
+// The model reads its own clock, and the real one when none is set
+func TestForumModelNow(t *testing.T) {
+	later := time.Date(2027, 6, 1, 0, 0, 0, 0, time.UTC)
+	if got := (ForumModel{Clock: fixedClock(later)}).now(); !got.Equal(later) {
+		t.Errorf("got %v; want the model clock's %v", got, later)
+	}
+	before := time.Now()
+	if got := (ForumModel{}).now(); got.Before(before) || got.After(time.Now()) {
+		t.Errorf("got %v without a clock; want the current time", got)
+	}
+}
+
+// Listings hide what has expired as of the clock, not as of the database
+func TestExpiryFollowsClock(t *testing.T) {
+	now := time.Date(2027, 6, 1, 0, 0, 0, 0, time.UTC)
+	var b queryBuilder
+	ForumFilter{}.apply(&b, ForumModel{Clock: fixedClock(now)}.now(), "")
+	if len(b.args) == 0 || b.args[0] != now {
+		t.Errorf("got args %v; want the clock's time first", b.args)
+	}
+}
+
+// SetClock() reaches every model that stamps times itself
+func TestModelsSetClock(t *testing.T) {
+	later := time.Date(2027, 6, 1, 0, 0, 0, 0, time.UTC)
+	models := NewModels(nil, nil)
+	models.SetClock(fixedClock(later))
+	for name, now := range map[string]time.Time{
+		"forums":  models.Forums.now(),
+		"changes": models.Changes.now(),
+		"imports": models.Imports.now(),
+		"jobs":    models.Jobs.now(),
+	} {
+		if !now.Equal(later) {
+			t.Errorf("got %v from %s; want the clock's %v", now, name, later)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+// Filename: internal/data/faqs.go
+
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"AWD_FinalProject.ryanarmstrong.net/internal/validator"
+)
+
+// MaxFAQs is the most FAQ entries a forum may have
+const MaxFAQs = 20
+
+var (
+	ErrTooManyFAQs = errors.New("too many faqs")
+	ErrFAQOrder    = errors.New("faq order must list every faq of the forum once")
+)
+
+// A FAQ is a question and answer shown with a forum, in position order
+type FAQ struct {
+	ID       int64  `json:"id"`
+	ForumID  int64  `json:"-"`
+	Question string `json:"question"`
+	Answer   string `json:"answer"`
+	Position int    `json:"position"`
+	Version  int32  `json:"version"`
+}
+
+func ValidateFAQ(v *validator.Validator, faq *FAQ) {
+	v.Check(faq.Question != "", "question", "required")
+	v.Check(len(faq.Question) <= FAQQuestionRule.MaxLength, "question", "max_bytes", FAQQuestionRule.MaxLength)
+
+	v.Check(faq.Answer != "", "answer", "required")
+	v.Check(len(faq.Answer) <= FAQAnswerRule.MaxLength, "answer", "max_bytes", FAQAnswerRule.MaxLength)
+}
+
+// Define a FAQModel which wraps a sql.DB connection pool
+type FAQModel struct {
+	DB     *sql.DB
+	ReadDB *sql.DB
+}
+
+// The lockForum() function locks the forum row for the rest of the
+// transaction so that changes to its FAQs happen one at a time
+func lockForum(ctx context.Context, tx *sql.Tx, forumID int64) error {
+	var id int64
+	err := tx.QueryRowContext(ctx, `SELECT id FROM forums WHERE id = $1 FOR UPDATE`, forumID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrRecordNotFound
+	}
+	return err
+}
+
+// Insert() adds a FAQ at the end of its forum's list
+func (m FAQModel) Insert(ctx context.Context, faq *FAQ) error {
+	ctx, span := startQuery(ctx, "FAQModel.Insert")
+	query := `
+		INSERT INTO forum_faqs (forum_id, question, answer, position)
+		SELECT $1, $2, $3, COUNT(*) + 1 FROM forum_faqs WHERE forum_id = $1
+		RETURNING id, position, version
+	`
+	// Create a context
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+	err := withTx(ctx, m.DB, func(tx *sql.Tx) error {
+		if err := lockForum(ctx, tx, faq.ForumID); err != nil {
+			return err
+		}
+		var count int
+		err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM forum_faqs WHERE forum_id = $1`, faq.ForumID).Scan(&count)
+		if err != nil {
+			return err
+		}
+		if count >= MaxFAQs {
+			return ErrTooManyFAQs
+		}
+		span.statement(query, faq.ForumID, faq.Question, faq.Answer)
+		return tx.QueryRowContext(ctx, query, faq.ForumID, faq.Question, faq.Answer).Scan(&faq.ID, &faq.Position, &faq.Version)
+	})
+	endQuery(span, rowsFor(err), err)
+	return err
+}
+
+// Get() returns a FAQ of a forum
+func (m FAQModel) Get(ctx context.Context, forumID int64, id int64) (*FAQ, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+	ctx, span := startQuery(ctx, "FAQModel.Get")
+	query := `
+		SELECT id, forum_id, question, answer, position, version
+		FROM forum_faqs
+		WHERE id = $1 AND forum_id = $2
+	`
+	// Create a context
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+	var faq FAQ
+	span.statement(query, id, forumID)
+	err := m.DB.QueryRowContext(ctx, query, id, forumID).Scan(&faq.ID, &faq.ForumID, &faq.Question, &faq.Answer, &faq.Position, &faq.Version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrRecordNotFound
+		}
+		endQuery(span, 0, err)
+		return nil, err
+	}
+	endQuery(span, 1, nil)
+	return &faq, nil
+}
+
+// GetAll() returns the FAQs of a forum in position order
+func (m FAQModel) GetAll(ctx context.Context, forumID int64) ([]*FAQ, error) {
+	ctx, span := startQuery(ctx, "FAQModel.GetAll")
+	query := `
+		SELECT id, forum_id, question, answer, position, version
+		FROM forum_faqs
+		WHERE forum_id = $1
+		ORDER BY position
+	`
+	db := m.DB
+	if m.ReadDB != nil {
+		db = m.ReadDB
+	}
+	// Create a context
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+	span.statement(query, forumID)
+	rows, err := db.QueryContext(ctx, query, forumID)
+	if err != nil {
+		endQuery(span, 0, err)
+		return nil, err
+	}
+	defer rows.Close()
+	faqs := []*FAQ{}
+	for rows.Next() {
+		var faq FAQ
+		if err := rows.Scan(&faq.ID, &faq.ForumID, &faq.Question, &faq.Answer, &faq.Position, &faq.Version); err != nil {
+			endQuery(span, len(faqs), err)
+			return nil, err
+		}
+		faqs = append(faqs, &faq)
+	}
+	err = rows.Err()
+	endQuery(span, len(faqs), err)
+	if err != nil {
+		return nil, err
+	}
+	return faqs, nil
+}
+
+// Update() saves the question and answer of a FAQ
+// Optimistic locking (version number)
+func (m FAQModel) Update(ctx context.Context, faq *FAQ) error {
+	ctx, span := startQuery(ctx, "FAQModel.Update")
+	query := `
+		UPDATE forum_faqs
+		SET question = $1, answer = $2, version = version + 1
+		WHERE id = $3 AND forum_id = $4
+		AND version = $5
+		RETURNING version
+	`
+	// Create a context
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+	span.statement(query, faq.Question, faq.Answer, faq.ID, faq.ForumID, faq.Version)
+	err := m.DB.QueryRowContext(ctx, query, faq.Question, faq.Answer, faq.ID, faq.ForumID, faq.Version).Scan(&faq.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrEditConflict
+	}
+	endQuery(span, rowsFor(err), err)
+	return err
+}
+
+// Delete() removes a FAQ and closes the gap it leaves in the positions
+func (m FAQModel) Delete(ctx context.Context, forumID int64, id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+	ctx, span := startQuery(ctx, "FAQModel.Delete")
+	// Create a context
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+	err := withTx(ctx, m.DB, func(tx *sql.Tx) error {
+		if err := lockForum(ctx, tx, forumID); err != nil {
+			return err
+		}
+		var position int
+		err := tx.QueryRowContext(ctx, `
+			DELETE FROM forum_faqs
+			WHERE id = $1 AND forum_id = $2
+			RETURNING position`, id, forumID).Scan(&position)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrRecordNotFound
+			}
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			UPDATE forum_faqs
+			SET position = position - 1
+			WHERE forum_id = $1 AND position > $2`, forumID, position)
+		return err
+	})
+	endQuery(span, rowsFor(err), err)
+	return err
+}
+
+// Reorder() puts the FAQs of a forum in the order of ids, which must list
+// every FAQ of the forum exactly once
+func (m FAQModel) Reorder(ctx context.Context, forumID int64, ids []int64) error {
+	ctx, span := startQuery(ctx, "FAQModel.Reorder")
+	query := `
+		UPDATE forum_faqs
+		SET position = o.position, version = version + 1
+		FROM unnest($2::bigint[]) WITH ORDINALITY AS o(id, position)
+		WHERE forum_faqs.id = o.id AND forum_faqs.forum_id = $1
+	`
+	// Create a context
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+	err := withTx(ctx, m.DB, func(tx *sql.Tx) error {
+		if err := lockForum(ctx, tx, forumID); err != nil {
+			return err
+		}
+		var count int
+		err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM forum_faqs WHERE forum_id = $1`, forumID).Scan(&count)
+		if err != nil {
+			return err
+		}
+		if count != len(ids) || !uniqueIDs(ids) {
+			return ErrFAQOrder
+		}
+		span.statement(query, forumID, ids)
+		result, err := tx.ExecContext(ctx, query, forumID, ids)
+		if err != nil {
+			return err
+		}
+		// Every id has to have matched one of this forum's FAQs
+		updated, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if int(updated) != len(ids) {
+			return ErrFAQOrder
+		}
+		return nil
+	})
+	endQuery(span, len(ids), err)
+	return err
+}
+
+// The uniqueIDs() function reports whether no id appears twice
+func uniqueIDs(ids []int64) bool {
+	seen := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			return false
+		}
+		seen[id] = true
+	}
+	return true
+}
@@ -0,0 +1,34 @@
+// Filename: internal/data/faqs_test.go
+
+package data
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"AWD_FinalProject.ryanarmstrong.net/internal/validator"
+)
+
+// ValidateFAQ() takes its limits from the FAQ rules and reports them in
+// the message arguments
+func TestValidateFAQ(t *testing.T) {
+	tests := []struct {
+		name string
+		faq  FAQ
+		want map[string][]interface{}
+	}{
+		{"longest", FAQ{Question: strings.Repeat("q", FAQQuestionRule.MaxLength), Answer: strings.Repeat("a", FAQAnswerRule.MaxLength)}, map[string][]interface{}{}},
+		{"long question", FAQ{Question: strings.Repeat("q", FAQQuestionRule.MaxLength+1), Answer: "a"}, map[string][]interface{}{"question": {FAQQuestionRule.MaxLength}}},
+		{"long answer", FAQ{Question: "q", Answer: strings.Repeat("a", FAQAnswerRule.MaxLength+1)}, map[string][]interface{}{"answer": {FAQAnswerRule.MaxLength}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateFAQ(v, &tt.faq)
+			if !reflect.DeepEqual(v.Args, tt.want) {
+				t.Errorf("got errors %v with args %v; want args %v", v.Errors, v.Args, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+// Filename: internal/data/feed.go
+
+package data
+
+import (
+	"context"
+	"time"
+)
+
+// A FeedEntry is a forum in the recently added feed along with the time
+// it last changed
+type FeedEntry struct {
+	Forum     *Forum
+	UpdatedAt time.Time
+}
+
+// GetRecent() returns the newest open, unexpired forums, newest first. Forums
+// have no updated_at column, so the last change recorded in the changes
+// feed stands in for it
+func (m ForumModel) GetRecent(ctx context.Context, limit int) ([]FeedEntry, error) {
+	ctx, span := startQuery(ctx, "ForumModel.GetRecent")
+	query := `
+		SELECT ` + forumColumns + `,
+		       COALESCE((SELECT MAX(changed_at) FROM forum_changes WHERE forum_changes.forum_id = forums.id), created_at)
+		FROM forums
+		WHERE expires_at > $2
+		AND archived_at IS NULL
+		ORDER BY created_at DESC, id DESC
+		LIMIT $1
+	`
+	// Create a context
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+	span.statement(query, limit, m.now())
+	rows, err := m.dbFor(true).QueryContext(ctx, query, limit, m.now())
+	if err != nil {
+		endQuery(span, 0, err)
+		return nil, err
+	}
+	defer rows.Close()
+	entries := []FeedEntry{}
+	for rows.Next() {
+		var updatedAt time.Time
+		forum, err := scanForum(rows, &updatedAt)
+		if err != nil {
+			endQuery(span, len(entries), err)
+			return nil, err
+		}
+		entries = append(entries, FeedEntry{Forum: forum, UpdatedAt: updatedAt})
+	}
+	if err = rows.Err(); err != nil {
+		endQuery(span, len(entries), err)
+		return nil, err
+	}
+	endQuery(span, len(entries), nil)
+	return entries, nil
+}
@@ -3,8 +3,10 @@
 package data
 
 import (
+	"fmt"
 	"math"
 	"strings"
+	"time"
 
 	"AWD_FinalProject.ryanarmstrong.net/internal/validator"
 )
@@ -12,38 +14,91 @@ import (
 type Filters struct {
 	Page     int
 	PageSize int
-	Sort     string
+	Sort     []string
 	SortList []string
+	// MaxOffset is how many rows deep a page may start, 0 for no limit
+	MaxOffset int
 }
 
 func ValidateFilers(v *validator.Validator, f Filters) {
 	// Check page and page_size parameter
-	v.Check(f.Page > 0, "page", "must be greater than zero")
-	v.Check(f.Page <= 1000, "page", "must be a maximum of 1000")
-	v.Check(f.PageSize > 0, "page_size", "must be greater than zero")
-	v.Check(f.PageSize <= 100, "page_size", "must be a maximum of 100")
-	// Check that the sort parameter matches a value in the acceptable sort list
-	v.Check(validator.In(f.Sort, f.SortList...), "sort", "invalid sort value")
+	v.Check(f.Page > 0, "page", "greater_than_zero")
+	v.Check(f.Page <= 1000, "page", "maximum", 1000)
+	v.Check(f.PageSize > 0, "page_size", "greater_than_zero")
+	v.Check(f.PageSize <= 100, "page_size", "maximum", 100)
+	// Deep offsets make Postgres walk every skipped row
+	if f.MaxOffset > 0 && v.Valid() {
+		v.Check(f.offset() <= f.MaxOffset, "page", "max_offset", f.MaxOffset)
+	}
+	// Check that every sort key matches a value in the acceptable sort list
+	// and that no column is sorted on twice (e.g "name" together with "-name")
+	v.Check(len(f.Sort) > 0, "sort", "required")
+	columns := make(map[string]bool)
+	for _, key := range f.Sort {
+		v.Check(validator.In(key, f.SortList...), "sort", "invalid_sort")
+		column := strings.TrimPrefix(key, "-")
+		v.Check(!columns[column], "sort", "contradictory_sort")
+		columns[column] = true
+	}
+}
+
+// Sort keys that order by an expression rather than a column of forums.
+// An expression that needs a value takes it from b
+var sortExpressions = map[string]func(b *queryBuilder, now time.Time) string{
+	// Names sort unaccented and case-insensitively, see migration 000014
+	"name": func(b *queryBuilder, now time.Time) string {
+		return "name_sort"
+	},
+	// Views over the last TrendingDays days, today included. The window
+	// is worked out from the model's clock, not NOW()
+	"trending": func(b *queryBuilder, now time.Time) string {
+		return fmt.Sprintf(`(SELECT COALESCE(SUM(count), 0) FROM forum_views
+		WHERE forum_views.forum_id = forums.id
+		AND forum_views.day > %s::date)`, b.arg(trendingSince(now)))
+	},
 }
 
-// The sortColumn() method safely extracts the sort field query parameter
-func (f Filters) sortColumn() string {
+// The sortColumn() method safely extracts the column from a sort key
+func (f Filters) sortColumn(b *queryBuilder, now time.Time, key string) string {
 	for _, safeValue := range f.SortList {
-		if f.Sort == safeValue {
-			return strings.TrimPrefix(f.Sort, "-")
+		if key == safeValue {
+			column := strings.TrimPrefix(key, "-")
+			if expression, ok := sortExpressions[column]; ok {
+				return expression(b, now)
+			}
+			return column
 		}
 	}
-	panic("unsafe sort parameter: " + f.Sort)
+	panic("unsafe sort parameter: " + key)
 }
 
-// The sortOrder() method determines whether we should sort by DESC/ASC
-func (f Filters) sortOrder() string {
-	if strings.HasPrefix(f.Sort, "-") {
+// The sortOrder() method determines whether we should sort a key by DESC/ASC
+func (f Filters) sortOrder(key string) string {
+	if strings.HasPrefix(key, "-") {
 		return "DESC"
 	}
 	return "ASC"
 }
 
+// The orderBy() method builds the ORDER BY clause from the sort keys in
+// sequence. The id is always appended last so that the ordering is stable.
+// Values the clause needs are added to b
+func (f Filters) orderBy(b *queryBuilder, now time.Time) string {
+	clauses := make([]string, 0, len(f.Sort)+1)
+	sortedByID := false
+	for _, key := range f.Sort {
+		column := f.sortColumn(b, now, key)
+		if column == "id" {
+			sortedByID = true
+		}
+		clauses = append(clauses, column+" "+f.sortOrder(key))
+	}
+	if !sortedByID {
+		clauses = append(clauses, "id ASC")
+	}
+	return strings.Join(clauses, ", ")
+}
+
 // The limit() method determines the LIMIT
 func (f Filters) limit() int {
 	return f.PageSize
@@ -54,6 +109,76 @@ func (f Filters) offset() int {
 	return (f.Page - 1) * f.PageSize
 }
 
+// A CreatedRange limits a listing to forums created strictly between
+// After and Before. A zero time leaves that side open
+type CreatedRange struct {
+	After  time.Time
+	Before time.Time
+}
+
+// A ForumFilter holds the filters of the forum listing. Empty values
+// don't filter
+type ForumFilter struct {
+	// Name and Level are matched as full-text searches
+	Name  string
+	Level string
+	// The forum must have every mode and language listed
+	Mode      []string
+	Languages []string
+	Created   CreatedRange
+	// Only forums with a free place
+	Available bool
+	// Archived lists the archived forums instead of the open ones
+	Archived bool
+	// Expired lists the expired forums instead of the current ones
+	Expired bool
+	// Phone holds the digits of a number to match against either the
+	// primary or the alternate number
+	Phone string
+}
+
+// The apply() method adds the conditions for the filters that are set to
+// b, along with hiding either the expired or the current forums and
+// either the archived or the open ones. Facet counts skip the filter on
+// their own dimension
+func (f ForumFilter) apply(b *queryBuilder, now time.Time, skip string) {
+	if f.Expired {
+		b.where("expires_at <= ?", now)
+	} else {
+		b.where("expires_at > ?", now)
+	}
+	if f.Archived {
+		b.where("archived_at IS NOT NULL")
+	} else {
+		b.where("archived_at IS NULL")
+	}
+	if f.Name != "" {
+		b.where("to_tsvector('simple_unaccent', name) @@ plainto_tsquery('simple_unaccent', ?)", f.Name)
+	}
+	if f.Level != "" && skip != "level" {
+		b.where("to_tsvector('simple_unaccent', level) @@ plainto_tsquery('simple_unaccent', ?)", f.Level)
+	}
+	if len(f.Mode) > 0 && skip != "mode" {
+		b.where("mode @> ?", f.Mode)
+	}
+	if len(f.Languages) > 0 && skip != "languages" {
+		b.where("languages @> ?", f.Languages)
+	}
+	if !f.Created.After.IsZero() {
+		b.where("created_at > ?", f.Created.After)
+	}
+	if !f.Created.Before.IsZero() {
+		b.where("created_at < ?", f.Created.Before)
+	}
+	if f.Available {
+		b.where("current_enrollment < student_capacity")
+	}
+	// Compare digits only, older rows may still hold other formats
+	if f.Phone != "" {
+		b.where("(regexp_replace(phone, '[^0-9]', '', 'g') = ? OR regexp_replace(phone_alt, '[^0-9]', '', 'g') = ?)", f.Phone, f.Phone)
+	}
+}
+
 // The Metadata type contains metadata to help with pagination
 type Metadata struct {
 	CurrentPage  int `json:"current_page,omitempty"`
@@ -61,6 +186,8 @@ type Metadata struct {
 	FirstPage    int `json:"first_page,omitempty"`
 	LastPage     int `json:"last_page,omitempty"`
 	TotalRecords int `json:"total_records,omitempty"`
+	// Facets maps each requested dimension to its value counts
+	Facets map[string]map[string]int `json:"facets,omitempty"`
 }
 
 // The calculateMetadata() function computes the values for the Metadata fields
@@ -0,0 +1,218 @@
+// Filename: internal/data/filters_test.go
+
+package data
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"AWD_FinalProject.ryanarmstrong.net/internal/validator"
+)
+
+// Leaving a filter out must not shift the placeholders of the ones after
+// it, and a facet count drops the filter on its own dimension
+func TestForumFilterApply(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	after := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		filter ForumFilter
+		skip   string
+		where  string
+		args   []interface{}
+	}{
+		{
+			name:   "no filters",
+			filter: ForumFilter{},
+			where:  "WHERE expires_at > $1\n\t\tAND archived_at IS NULL",
+			args:   []interface{}{now},
+		},
+		{
+			name:   "expired and archived",
+			filter: ForumFilter{Expired: true, Archived: true},
+			where:  "WHERE expires_at <= $1\n\t\tAND archived_at IS NOT NULL",
+			args:   []interface{}{now},
+		},
+		{
+			name:   "level left out",
+			filter: ForumFilter{Name: "study", Mode: []string{"online"}, Created: CreatedRange{After: after}},
+			where: "WHERE expires_at > $1\n\t\tAND archived_at IS NULL" +
+				"\n\t\tAND to_tsvector('simple_unaccent', name) @@ plainto_tsquery('simple_unaccent', $2)" +
+				"\n\t\tAND mode @> $3" +
+				"\n\t\tAND created_at > $4",
+			args: []interface{}{now, "study", []string{"online"}, after},
+		},
+		{
+			name:   "mode facet skips the mode filter",
+			filter: ForumFilter{Mode: []string{"online"}, Languages: []string{"es"}},
+			skip:   "mode",
+			where:  "WHERE expires_at > $1\n\t\tAND archived_at IS NULL\n\t\tAND languages @> $2",
+			args:   []interface{}{now, []string{"es"}},
+		},
+		{
+			name:   "available",
+			filter: ForumFilter{Available: true},
+			where:  "WHERE expires_at > $1\n\t\tAND archived_at IS NULL\n\t\tAND current_enrollment < student_capacity",
+			args:   []interface{}{now},
+		},
+		{
+			name:   "phone matches either number",
+			filter: ForumFilter{Phone: "5012234455"},
+			where: "WHERE expires_at > $1\n\t\tAND archived_at IS NULL" +
+				"\n\t\tAND (regexp_replace(phone, '[^0-9]', '', 'g') = $2 OR regexp_replace(phone_alt, '[^0-9]', '', 'g') = $3)",
+			args: []interface{}{now, "5012234455", "5012234455"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b queryBuilder
+			tt.filter.apply(&b, now, tt.skip)
+			if got := b.whereClause(); got != tt.where {
+				t.Errorf("got clause\n%s\nwant\n%s", got, tt.where)
+			}
+			if !reflect.DeepEqual(b.args, tt.args) {
+				t.Errorf("got args %v; want %v", b.args, tt.args)
+			}
+		})
+	}
+}
+
+// The same regexp_replace() the phone filter runs on the stored columns
+var sqlNonDigits = regexp.MustCompile(`[^0-9]`)
+
+// A number searched for in any format finds a forum that has it as its
+// primary or its alternate number, stored the way Normalize() writes it
+func TestForumFilterPhoneMatch(t *testing.T) {
+	stored := fixture(t, "secondary").Phones
+	stored.Normalize()
+	columns := map[string]string{
+		"phone":     sqlNonDigits.ReplaceAllString(stored.Primary, ""),
+		"phone_alt": sqlNonDigits.ReplaceAllString(stored.Alternate, ""),
+	}
+
+	tests := []struct {
+		search string
+		match  bool
+	}{
+		{search: "501-322-1100", match: true},
+		{search: "501 322 1100", match: true},
+		{search: "٥٠١٣٢٢١١٠٠", match: true},
+		{search: "+501.610.9988", match: true},
+		{search: "(501) 6109988", match: true},
+		{search: "501-322-1101", match: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.search, func(t *testing.T) {
+			var b queryBuilder
+			ForumFilter{Phone: PhoneDigits(tt.search)}.apply(&b, time.Now(), "")
+			// The phone condition binds $2 against phone and $3 against
+			// phone_alt, and the forum matches when either is equal
+			cond := "(regexp_replace(phone, '[^0-9]', '', 'g') = $2 OR regexp_replace(phone_alt, '[^0-9]', '', 'g') = $3)"
+			if !strings.Contains(b.whereClause(), cond) {
+				t.Fatalf("got clause\n%s\nwant it to hold\n%s", b.whereClause(), cond)
+			}
+			match := b.args[1] == columns["phone"] || b.args[2] == columns["phone_alt"]
+			if match != tt.match {
+				t.Errorf("got match %t searching %v against %v; want %t", match, b.args[1:], columns, tt.match)
+			}
+		})
+	}
+}
+
+func TestFiltersOrderBy(t *testing.T) {
+	sortList := []string{"id", "name", "level", "-id", "-name", "-level", "-trending"}
+	tests := []struct {
+		name string
+		sort []string
+		want string
+	}{
+		{"id", []string{"id"}, "id ASC"},
+		{"id descending", []string{"-id"}, "id DESC"},
+		{"id is added last", []string{"level"}, "level ASC, id ASC"},
+		{"keys keep their order", []string{"-level", "name"}, "level DESC, name_sort ASC, id ASC"},
+		{"id in the middle is not repeated", []string{"level", "-id", "name"}, "level ASC, id DESC, name_sort ASC"},
+	}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := Filters{Sort: tt.sort, SortList: sortList}
+			b := &queryBuilder{}
+			if got := f.orderBy(b, now); got != tt.want {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+			if len(b.args) != 0 {
+				t.Errorf("got args %v; want none", b.args)
+			}
+		})
+	}
+}
+
+// The trending window is cut from the clock handed in, on the same UTC
+// days the view counter uses, and travels as an argument after the ones
+// already in the builder
+func TestFiltersOrderByTrending(t *testing.T) {
+	f := Filters{Sort: []string{"-trending"}, SortList: []string{"-trending"}}
+	tests := []struct {
+		now  time.Time
+		want string
+	}{
+		{time.Date(2026, 3, 8, 12, 0, 0, 0, time.UTC), "2026-03-01"},
+		{time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC), "2026-03-01"},
+		// 23:30 in Belize is already the next day in UTC
+		{time.Date(2026, 3, 8, 23, 30, 0, 0, time.FixedZone("CST", -6*60*60)), "2026-03-02"},
+	}
+	for _, tt := range tests {
+		b := &queryBuilder{}
+		b.where("expires_at > ?", tt.now)
+		got := f.orderBy(b, tt.now)
+		if !strings.Contains(got, "forum_views.day > $2::date") || !strings.HasSuffix(got, " DESC, id ASC") {
+			t.Errorf("got %q; want the window in $2", got)
+		}
+		if strings.Contains(got, "NOW()") {
+			t.Errorf("got %q; want no NOW()", got)
+		}
+		if len(b.args) != 2 || b.args[1] != tt.want {
+			t.Errorf("at %v got args %v; want the window to start after %s", tt.now, b.args, tt.want)
+		}
+	}
+}
+
+// A key that isn't in the safe list never reaches the query
+func TestFiltersOrderByUnsafe(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("orderBy() did not panic")
+		}
+	}()
+	f := Filters{Sort: []string{"name; DROP TABLE forums"}, SortList: []string{"name"}}
+	f.orderBy(&queryBuilder{}, time.Now())
+}
+
+func TestValidateFiltersSort(t *testing.T) {
+	sortList := []string{"id", "name", "-id", "-name"}
+	tests := []struct {
+		name string
+		sort []string
+		want string
+	}{
+		{"one key", []string{"name"}, ""},
+		{"several keys", []string{"-name", "id"}, ""},
+		{"no keys", []string{}, "required"},
+		{"unknown key", []string{"level"}, "invalid_sort"},
+		{"same column twice", []string{"name", "-name"}, "contradictory_sort"},
+		{"same key twice", []string{"id", "id"}, "contradictory_sort"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateFilers(v, Filters{Page: 1, PageSize: 20, Sort: tt.sort, SortList: sortList})
+			if got := v.Errors["sort"]; got != tt.want {
+				t.Errorf("got sort error %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
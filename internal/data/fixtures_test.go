@@ -0,0 +1,105 @@
+// Filename: internal/data/fixtures_test.go
+
+package data
+
+import (
+	"encoding/json"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"AWD_FinalProject.ryanarmstrong.net/internal/validator"
+)
+
+// A forumFixture is one entry of testdata/forums.json, a forum under the
+// name tests look it up by
+type forumFixture struct {
+	Fixture string `json:"fixture"`
+	Forum
+}
+
+// The loadFixtures() function reads testdata/forums.json and returns the
+// fixture names alongside the forums, in file order. The forums are
+// numbered from 1 and given a public id to match, so every run sees the
+// same rows. Each call decodes the file again, so a test is free to
+// change what it gets
+func loadFixtures(t *testing.T) ([]string, []*Forum) {
+	t.Helper()
+	js, err := os.ReadFile("testdata/forums.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []forumFixture
+	if err := json.Unmarshal(js, &entries); err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(entries))
+	forums := make([]*Forum, 0, len(entries))
+	for i, entry := range entries {
+		forum := entry.Forum
+		forum.ID = int64(i + 1)
+		forum.PublicID = strings.Repeat(string(rune('a'+i)), 10)
+		forum.Version = 1
+		names = append(names, entry.Fixture)
+		forums = append(forums, &forum)
+	}
+	return names, forums
+}
+
+// The fixtures() function returns every fixture forum
+func fixtures(t *testing.T) []*Forum {
+	t.Helper()
+	_, forums := loadFixtures(t)
+	return forums
+}
+
+// The fixture() function returns the fixture forum called name
+func fixture(t *testing.T, name string) *Forum {
+	t.Helper()
+	names, forums := loadFixtures(t)
+	i := slices.Index(names, name)
+	if i < 0 {
+		t.Fatalf("no fixture called %q", name)
+	}
+	return forums[i]
+}
+
+// The load() method empties db and fills it with forums
+func (db *memDB) load(forums []*Forum) {
+	db.tables = memTables{forums: map[int64]string{}, faqs: map[int64]int64{}}
+	for _, forum := range forums {
+		db.tables.forums[forum.ID] = forum.PublicID
+	}
+}
+
+// Every fixture is a forum ValidateForum() accepts, and between them they
+// cover each level, the limits of every field and an archived forum
+func TestFixtures(t *testing.T) {
+	forums := fixtures(t)
+	levels := map[string]bool{}
+	var archived, longest, bare bool
+	for _, forum := range forums {
+		v := validator.New()
+		ValidateForum(v, forum)
+		if !v.Valid() {
+			t.Errorf("got errors %v for fixture %d", v.Errors, forum.ID)
+		}
+		levels[forum.Level] = true
+		archived = archived || forum.Archived()
+		longest = longest || (len(forum.Name) == ForumNameRule.MaxLength &&
+			len(forum.Address) == ForumAddressRule.MaxLength &&
+			len(forum.Mode) == ForumModeRule.MaxItems &&
+			len(forum.Languages) == ForumLanguagesRule.MaxItems)
+		bare = bare || (forum.Phones.Extension == "" && forum.Phones.Alternate == "" &&
+			forum.StudentCapacity == nil && forum.CurrentEnrollment == nil)
+	}
+	for _, level := range []string{"primary", "secondary", "tertiary"} {
+		if !levels[level] {
+			t.Errorf("got no %s fixture", level)
+		}
+	}
+	if !archived || !longest || !bare {
+		t.Errorf("got archived %t, longest %t, no optional fields %t; want a fixture for each", archived, longest, bare)
+	}
+}
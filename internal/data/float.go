@@ -0,0 +1,36 @@
+// Filename: internal/data/float.go
+
+package data
+
+import (
+	"math"
+	"strconv"
+)
+
+// The number of decimal places computed values are written out with
+const floatPrecision = 4
+
+// A Float is a computed number such as a search rank. It is written out
+// rounded to floatPrecision places, and NaN or an infinity from a bad
+// calculation is written as null instead of failing the whole response
+type Float float64
+
+// MarshalJSON() writes the rounded value, or null when it isn't finite
+func (f Float) MarshalJSON() ([]byte, error) {
+	value := float64(f)
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return []byte("null"), nil
+	}
+	return strconv.AppendFloat(nil, round(value), 'f', -1, 64), nil
+}
+
+// The round() function cuts a value down to floatPrecision places. Once
+// the scaled value passes 2^53 a float64 has no places left to cut, and
+// scaling would only lose precision
+func round(value float64) float64 {
+	scale := math.Pow10(floatPrecision)
+	if math.Abs(value)*scale >= 1<<53 {
+		return value
+	}
+	return math.Round(value*scale) / scale
+}
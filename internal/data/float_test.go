@@ -0,0 +1,56 @@
+// Filename: internal/data/float_test.go
+
+package data
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestFloatMarshalJSON(t *testing.T) {
+	tests := []struct {
+		value float64
+		want  string
+	}{
+		{0, "0"},
+		{1, "1"},
+		{0.5, "0.5"},
+		{0.123456, "0.1235"},
+		{0.00004, "0"},
+		{0.00005, "0.0001"},
+		{-2.71828, "-2.7183"},
+		{0.1 + 0.2, "0.3"},
+		// Values too large for four more places come out unchanged
+		{1e21, "1000000000000000000000"},
+		{1234567890123.4567, "1234567890123.4568"},
+		{math.NaN(), "null"},
+		{math.Inf(1), "null"},
+		{math.Inf(-1), "null"},
+	}
+	for _, tt := range tests {
+		js, err := json.Marshal(Float(tt.value))
+		if err != nil {
+			t.Errorf("%v: %v", tt.value, err)
+			continue
+		}
+		if string(js) != tt.want {
+			t.Errorf("%v: got %s; want %s", tt.value, js, tt.want)
+		}
+	}
+}
+
+// A bad rank doesn't spoil the rest of the forum
+func TestFloatInForum(t *testing.T) {
+	rank := Float(math.NaN())
+	js, err := json.Marshal(struct {
+		Rank *Float `json:"rank"`
+		Name string `json:"name"`
+	}{&rank, "Study Group"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"rank":null,"name":"Study Group"}`; string(js) != want {
+		t.Errorf("got %s; want %s", js, want)
+	}
+}
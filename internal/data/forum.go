@@ -5,225 +5,621 @@ package data
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"AWD_FinalProject.ryanarmstrong.net/internal/validator"
-	"github.com/lib/pq"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 type Forum struct {
-	ID        int64     `json:"id"` // Struct tags
-	CreatedAt time.Time `json:"-"`  // doesn't display to client
+	ID        int64     `json:"id,omitempty"` // Struct tags
+	PublicID  string    `json:"public_id"`
+	CreatedAt time.Time `json:"-"` // doesn't display to client
 	Name      string    `json:"name"`
 	Level     string    `json:"level"`
 	Contact   string    `json:"contact"`
-	Phone     string    `json:"phone"`
+	Phones    Phones    `json:"phones,omitzero"`
 	Email     string    `json:"email,omitempty"`
 	Website   string    `json:"website,omitempty"`
 	Address   string    `json:"address"`
-	Mode      []string  `json:"mode"`
-	Version   int32     `json:"version"`
+	Mode      Modes     `json:"mode"`
+	Languages Languages `json:"languages"`
+	// PublicContact controls whether anonymous viewers see the phone and email
+	PublicContact bool `json:"public_contact"`
+	// StudentCapacity and CurrentEnrollment are optional. EnrollmentPrivate
+	// hides the enrollment from anonymous viewers
+	StudentCapacity   *int32 `json:"student_capacity,omitempty"`
+	CurrentEnrollment *int32 `json:"current_enrollment,omitempty"`
+	EnrollmentPrivate bool   `json:"enrollment_private"`
+	// An archived forum is closed for the time being. ArchivedAt is null
+	// while it is open
+	ArchivedAt    NullTime  `json:"archived_at"`
+	ArchiveReason string    `json:"archive_reason,omitempty"`
+	ExpiresAt     Timestamp `json:"expires_at"`
+	Version       int32     `json:"version"`
+	// Rank and Headline are only filled in for search queries
+	Rank     *Float  `json:"rank,omitempty"`
+	Headline *string `json:"headline,omitempty"`
+	// FAQs is only filled in when the client asks for ?include=faqs
+	FAQs []*FAQ `json:"faqs,omitempty"`
+	// ExternalRef is only set on Insert() by the registry importer
+	ExternalRef string `json:"-"`
+}
+
+// MarshalJSON() writes the forum along with the deprecated flat phone,
+// which repeats phones.primary for clients that haven't moved over yet
+func (forum Forum) MarshalJSON() ([]byte, error) {
+	type plain Forum
+	return json.Marshal(struct {
+		plain
+		Phone string `json:"phone,omitempty"`
+	}{plain(forum), forum.Phones.Primary})
+}
+
+// A ForumSummary is the short form of a Forum sent in listings. The full
+// representation is only sent for a single forum
+type ForumSummary struct {
+	ID        int64     `json:"id,omitempty"`
+	PublicID  string    `json:"public_id"`
+	Name      string    `json:"name"`
+	Level     string    `json:"level"`
+	Mode      Modes     `json:"mode"`
+	Languages Languages `json:"languages"`
+	Archived  bool      `json:"archived"`
+	Rank      *Float    `json:"rank,omitempty"`
+	Headline  *string   `json:"headline,omitempty"`
+}
+
+// Summary() returns the listing form of the forum. Redact() the forum
+// first, the summary carries over whatever is left
+func (forum *Forum) Summary() ForumSummary {
+	return ForumSummary{
+		ID:        forum.ID,
+		PublicID:  forum.PublicID,
+		Name:      forum.Name,
+		Level:     forum.Level,
+		Mode:      forum.Mode,
+		Languages: forum.Languages,
+		Archived:  forum.Archived(),
+		Rank:      forum.Rank,
+		Headline:  forum.Headline,
+	}
+}
+
+// A Viewer is the relationship between the client and the forum shown
+type Viewer int
+
+const (
+	PublicViewer Viewer = iota
+	OwnerViewer
+	AdminViewer
+)
+
+// Redact() hides the serial id from public viewers, along with the direct
+// contact details when the forum has not made them public. Owners and
+// admins always see everything
+func (forum *Forum) Redact(viewer Viewer) {
+	if viewer != PublicViewer {
+		return
+	}
+	forum.ID = 0
+	if forum.EnrollmentPrivate {
+		forum.CurrentEnrollment = nil
+	}
+	if forum.PublicContact {
+		return
+	}
+	forum.Phones = Phones{}
+	forum.Email = ""
 }
 
 func ValidateForum(v *validator.Validator, forum *Forum) {
 	// Use the Check() method to execute our validation checks
-	v.Check(forum.Name != "", "name", "must be provided")
-	v.Check(len(forum.Name) <= 200, "name", "must not be more than 200 bytes long")
+	// The limits come from the field rules in schema.go and the messages
+	// are keys into the translation files in internal/i18n
+	v.Check(forum.Name != "", "name", "required")
+	v.Check(len(forum.Name) <= ForumNameRule.MaxLength, "name", "max_bytes", ForumNameRule.MaxLength)
+
+	v.Check(forum.Level != "", "level", "required")
+	v.Check(len(forum.Level) <= ForumLevelRule.MaxLength, "level", "max_bytes", ForumLevelRule.MaxLength)
 
-	v.Check(forum.Level != "", "level", "must be provided")
-	v.Check(len(forum.Level) <= 200, "level", "must not be more than 200 bytes long")
+	v.Check(forum.Contact != "", "contact", "required")
+	v.Check(len(forum.Contact) <= ForumContactRule.MaxLength, "contact", "max_bytes", ForumContactRule.MaxLength)
 
-	v.Check(forum.Contact != "", "contact", "must be provided")
-	v.Check(len(forum.Contact) <= 200, "contact", "must not be more than 200 bytes long")
+	validatePhones(v, forum.Phones)
 
-	v.Check(forum.Phone != "", "phone", "must be provided")
-	v.Check(validator.Matches(forum.Phone, validator.PhoneRX), "phone", "must be a valid phone number")
+	v.Check(forum.Email != "", "email", "required")
+	v.Check(validator.Matches(forum.Email, validator.EmailRX), "email", "invalid_email")
 
-	v.Check(forum.Email != "", "email", "must be provided")
-	v.Check(validator.Matches(forum.Email, validator.EmailRX), "email", "must be a valid email address")
+	v.Check(forum.Website != "", "website", "required")
+	v.Check(validator.ValidWebsite(forum.Website), "website", "invalid_url")
 
-	v.Check(forum.Website != "", "website", "must be provided")
-	v.Check(validator.ValidWebsite(forum.Website), "website", "must be a valid URL")
+	v.Check(forum.Address != "", "address", "required")
+	v.Check(len(forum.Address) <= ForumAddressRule.MaxLength, "address", "max_bytes", ForumAddressRule.MaxLength)
 
-	v.Check(forum.Address != "", "address", "must be provided")
-	v.Check(len(forum.Address) <= 500, "address", "must not be more than 500 bytes long")
+	v.Check(forum.Mode != nil, "mode", "required")
+	v.Check(len(forum.Mode) >= ForumModeRule.MinItems, "mode", "min_entries", ForumModeRule.MinItems)
+	v.Check(len(forum.Mode) <= ForumModeRule.MaxItems, "mode", "max_entries", ForumModeRule.MaxItems)
+	v.Check(validator.Unique(forum.Mode), "mode", "duplicate_entries")
 
-	v.Check(forum.Mode != nil, "mode", "must be provided")
-	v.Check(len(forum.Mode) >= 1, "mode", "must contain at least 1 entry")
-	v.Check(len(forum.Mode) <= 5, "mode", "must contain at most 5 entries")
-	v.Check(validator.Unique(forum.Mode), "mode", "must not contain duplicate entries")
+	v.Check(forum.Languages != nil, "languages", "required")
+	v.Check(len(forum.Languages) >= ForumLanguagesRule.MinItems, "languages", "min_entries", ForumLanguagesRule.MinItems)
+	v.Check(len(forum.Languages) <= ForumLanguagesRule.MaxItems, "languages", "max_entries", ForumLanguagesRule.MaxItems)
+	v.Check(validator.Unique(forum.Languages), "languages", "duplicate_entries")
+	v.Check(validator.EachIn(forum.Languages, LanguageList...), "languages", "invalid_language", strings.Join(LanguageList, ", "))
+
+	// Capacity and enrollment are optional, but enrollment is only
+	// meaningful against a capacity and can't go over it
+	if forum.StudentCapacity != nil {
+		capacity := int(*forum.StudentCapacity)
+		min, max := *ForumStudentCapacityRule.Minimum, *ForumStudentCapacityRule.Maximum
+		v.Check(capacity >= min && capacity <= max, "student_capacity", "between", min, max)
+	}
+	if forum.CurrentEnrollment != nil {
+		enrollment := int(*forum.CurrentEnrollment)
+		v.Check(enrollment >= *ForumCurrentEnrollmentRule.Minimum, "current_enrollment", "minimum", *ForumCurrentEnrollmentRule.Minimum)
+		v.Check(forum.StudentCapacity != nil, "current_enrollment", "requires_capacity")
+		if forum.StudentCapacity != nil {
+			v.Check(enrollment <= int(*forum.StudentCapacity), "current_enrollment", "exceeds_capacity")
+		}
+	}
+
+	// A reason only goes with an archived forum
+	if forum.Archived() {
+		v.Check(forum.ArchiveReason != "", "reason", "required")
+		v.Check(len(forum.ArchiveReason) <= ArchiveReasonRule.MaxLength, "reason", "max_bytes", ArchiveReasonRule.MaxLength)
+	} else {
+		v.Check(forum.ArchiveReason == "", "reason", "archived_only")
+	}
 }
 
-// Define a ForumModel which wraps a sql.DB connection pool
+// Archived() reports whether the forum is archived
+func (forum *Forum) Archived() bool {
+	return !forum.ArchivedAt.IsZero()
+}
+
+// Define a ForumModel which wraps a sql.DB connection pool. ReadDB is an
+// optional replica pool for read-only queries
 type ForumModel struct {
-	DB *sql.DB
+	DB        *sql.DB
+	ReadDB    *sql.DB
+	Highlight Highlight
+	// ListTimeout is how long the server lets a GetAll() or Search() query
+	// run
+	ListTimeout time.Duration
+	// Clock decides when forums expire, see clock.go
+	Clock Clock
+}
+
+// forumColumns are the columns of a full forum, in the order scanForum()
+// reads them. Queries select them first and any extra columns after
+const forumColumns = `id, public_id, created_at, name, level, contact, phone, phone_ext, phone_alt, email, website, address, mode, languages, public_contact, student_capacity, current_enrollment, enrollment_private, archived_at, archive_reason, expires_at, version`
+
+// A rowScanner is a *sql.Row or *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// The scanForum() function reads the forumColumns of a row into a new
+// Forum, followed by any extra columns into extra
+func scanForum(row rowScanner, extra ...interface{}) (*Forum, error) {
+	var forum Forum
+	dest := []interface{}{
+		&forum.ID,
+		&forum.PublicID,
+		&forum.CreatedAt,
+		&forum.Name,
+		&forum.Level,
+		&forum.Contact,
+		&forum.Phones.Primary,
+		&forum.Phones.Extension,
+		&forum.Phones.Alternate,
+		&forum.Email,
+		&forum.Website,
+		&forum.Address,
+		&forum.Mode,
+		&forum.Languages,
+		&forum.PublicContact,
+		&forum.StudentCapacity,
+		&forum.CurrentEnrollment,
+		&forum.EnrollmentPrivate,
+		&forum.ArchivedAt,
+		&forum.ArchiveReason,
+		&forum.ExpiresAt,
+		&forum.Version,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	return &forum, nil
+}
+
+// DefaultListTimeout is used when ListTimeout is not set
+const DefaultListTimeout = 5 * time.Second
+
+// The dbFor() method picks the pool for a query. Read-only queries go to
+// the replica when there is one, everything else to the primary
+func (m ForumModel) dbFor(readonly bool) *sql.DB {
+	if readonly && m.ReadDB != nil {
+		return m.ReadDB
+	}
+	return m.DB
+}
+
+// Highlight holds the tags placed around matching terms in search headlines
+type Highlight struct {
+	Start string
+	Stop  string
+}
+
+// The options() method builds the ts_headline() options string.
+// HighlightAll makes the whole name the headline since names are short
+func (h Highlight) options() string {
+	return fmt.Sprintf(`StartSel="%s", StopSel="%s", HighlightAll=true`, h.Start, h.Stop)
 }
 
 // Insert() allows us to create a new Forum
-func (m ForumModel) Insert(forum *Forum) error {
+func (m ForumModel) Insert(ctx context.Context, forum *Forum) error {
+	ctx, span := startQuery(ctx, "ForumModel.Insert")
 	query := `
-		INSERT INTO forums (name, level, contact, phone, email, website, address, mode)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		RETURNING id, created_at, version
+		INSERT INTO forums (name, level, contact, phone, phone_ext, phone_alt, email, website, address, mode, languages, public_contact,
+		                    student_capacity, current_enrollment, enrollment_private, external_ref, expires_at, public_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, NULLIF($16, ''), $17, $18)
+		RETURNING id, created_at, expires_at, version
 	`
 	// Create a context
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	// Cleanup to prevent memory leaks
 	defer cancel()
 	// Collect the data fields into a slice
 	args := []interface{}{
 		forum.Name, forum.Level,
-		forum.Contact, forum.Phone,
+		forum.Contact, forum.Phones.Primary,
+		forum.Phones.Extension, forum.Phones.Alternate,
 		forum.Email, forum.Website,
-		forum.Address, pq.Array(forum.Mode),
+		forum.Address, []string(forum.Mode),
+		[]string(forum.Languages), forum.PublicContact,
+		forum.StudentCapacity, forum.CurrentEnrollment,
+		forum.EnrollmentPrivate, forum.ExternalRef,
+		m.now().AddDate(1, 0, 0), nil,
+	}
+	// Create the Forum and record it in the changes feed together. A new
+	// public id is drawn if the last one was already taken
+	var err error
+	for attempt := 0; attempt < publicIDAttempts; attempt++ {
+		publicID := newPublicID()
+		args[len(args)-1] = publicID
+		err = withTx(ctx, m.DB, func(tx *sql.Tx) error {
+			span.statement(query, args...)
+			err := tx.QueryRowContext(ctx, query, args...).Scan(&forum.ID, &forum.CreatedAt, &forum.ExpiresAt, &forum.Version)
+			if err != nil {
+				return err
+			}
+			return recordChange(ctx, tx, forum.ID, publicID, ChangeCreated)
+		})
+		if !isPublicIDCollision(err) {
+			if err == nil {
+				forum.PublicID = publicID
+			}
+			break
+		}
 	}
-	return m.DB.QueryRowContext(ctx, query, args...).Scan(&forum.ID, &forum.CreatedAt, &forum.Version)
+	endQuery(span, rowsFor(err), err)
+	return err
+}
+
+// Get() allows us to recieve a specific Forum. It may read from the
+// replica, so a change made moments ago might not show yet
+func (m ForumModel) Get(ctx context.Context, id int64) (*Forum, error) {
+	return m.get(ctx, "ForumModel.Get", m.dbFor(true), id)
 }
 
-// Get() allows us to recieve a specific Forum
-func (m ForumModel) Get(id int64) (*Forum, error) {
+// GetPrimary() is Get() against the primary. Use it to load a forum that
+// is about to be written so the version we check is the latest one
+func (m ForumModel) GetPrimary(ctx context.Context, id int64) (*Forum, error) {
+	return m.get(ctx, "ForumModel.GetPrimary", m.dbFor(false), id)
+}
+
+func (m ForumModel) get(ctx context.Context, spanName string, db *sql.DB, id int64) (*Forum, error) {
 	// Ensure that there is a valid id
 	if id < 1 {
 		return nil, ErrRecordNotFound
 	}
 	// Create the query
 	query := `
-		SELECT id, created_at, name, level, contact, phone, email, website, address, mode, version
+		SELECT ` + forumColumns + `
 		FROM forums
 		WHERE id = $1
 	`
-	// Declare a Forum variable to hold the returned data
-	var forum Forum
+	ctx, span := startQuery(ctx, spanName)
 	// Create a context
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	// Cleanup to prevent memory leaks
 	defer cancel()
 	// Execute the query using QueryRow()
-	err := m.DB.QueryRowContext(ctx, query, id).Scan(
-		&forum.ID,
-		&forum.CreatedAt,
-		&forum.Name,
-		&forum.Level,
-		&forum.Contact,
-		&forum.Phone,
-		&forum.Email,
-		&forum.Website,
-		&forum.Address,
-		pq.Array(&forum.Mode),
-		&forum.Version,
-	)
+	span.statement(query, id)
+	forum, err := scanForum(db.QueryRowContext(ctx, query, id))
 	// Handle any errors
 	if err != nil {
-		// Check the type of error
-		switch {
-		case errors.Is(err, sql.ErrNoRows):
-			return nil, ErrRecordNotFound
-		default:
-			return nil, err
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrRecordNotFound
 		}
+		endQuery(span, 0, err)
+		// Check the type of error
+		return nil, err
 	}
+	endQuery(span, 1, nil)
 	// Success
-	return &forum, nil
+	return forum, nil
+}
+
+// MaxGetMany is the most ids GetMany() fetches in one call
+const MaxGetMany = 100
+
+var ErrTooManyIDs = errors.New("too many ids")
+
+// GetMany() fetches the forums with the given ids in a single query. The
+// forums come back in the order of ids, with a repeated id kept at its
+// first position. The ids that don't exist are returned separately, in
+// the same order
+func (m ForumModel) GetMany(ctx context.Context, ids []int64) ([]*Forum, []int64, error) {
+	if len(ids) > MaxGetMany {
+		return nil, nil, ErrTooManyIDs
+	}
+	if len(ids) == 0 {
+		return []*Forum{}, []int64{}, nil
+	}
+	ctx, span := startQuery(ctx, "ForumModel.GetMany")
+	query := `
+		SELECT ` + forumColumns + `
+		FROM forums
+		WHERE id = ANY($1)
+	`
+	// Create a context
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+	span.statement(query, ids)
+	rows, err := m.dbFor(true).QueryContext(ctx, query, ids)
+	if err != nil {
+		endQuery(span, 0, err)
+		return nil, nil, err
+	}
+	defer rows.Close()
+	found := make(map[int64]*Forum, len(ids))
+	for rows.Next() {
+		forum, err := scanForum(rows)
+		if err != nil {
+			endQuery(span, len(found), err)
+			return nil, nil, err
+		}
+		found[forum.ID] = forum
+	}
+	if err = rows.Err(); err != nil {
+		endQuery(span, len(found), err)
+		return nil, nil, err
+	}
+	forums, missing := inIDOrder(ids, found)
+	endQuery(span, len(forums), nil)
+	return forums, missing, nil
+}
+
+// The inIDOrder() function puts the forums found back in the order they
+// were asked for, and lists the ids that weren't found
+func inIDOrder(ids []int64, found map[int64]*Forum) ([]*Forum, []int64) {
+	forums := make([]*Forum, 0, len(found))
+	missing := []int64{}
+	seen := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if forum, ok := found[id]; ok {
+			forums = append(forums, forum)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	return forums, missing
 }
 
 // Update() allows us to edit/alter a specific Forum
 // Optimistic locking (version number)
-func (m ForumModel) Update(forum *Forum) error {
+func (m ForumModel) Update(ctx context.Context, forum *Forum) error {
+	ctx, span := startQuery(ctx, "ForumModel.Update")
 	// Create a query
 	query := `
 		UPDATE forums
 		SET name = $1, level = $2, contact = $3, 
 			phone = $4, email = $5, website = $6,
-			address = $7, mode = $8, version = version + 1
-		WHERE id = $9
-		AND version = $10
+			address = $7, mode = $8, public_contact = $9,
+			student_capacity = $10, current_enrollment = $11, enrollment_private = $12,
+			languages = $13, phone_ext = $14, phone_alt = $15,
+			archived_at = $16, archive_reason = $17,
+			version = version + 1
+		WHERE id = $18
+		AND version = $19
 		RETURNING version
 	`
 	// Create a context
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	// Cleanup to prevent memory leaks
 	defer cancel()
 	args := []interface{}{
 		forum.Name,
 		forum.Level,
 		forum.Contact,
-		forum.Phone,
+		forum.Phones.Primary,
 		forum.Email,
 		forum.Website,
 		forum.Address,
-		pq.Array(forum.Mode),
+		[]string(forum.Mode),
+		forum.PublicContact,
+		forum.StudentCapacity,
+		forum.CurrentEnrollment,
+		forum.EnrollmentPrivate,
+		[]string(forum.Languages),
+		forum.Phones.Extension,
+		forum.Phones.Alternate,
+		forum.ArchivedAt,
+		forum.ArchiveReason,
 		forum.ID,
 		forum.Version,
 	}
-	// Check for edit conflicts
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&forum.Version)
-	if err != nil {
-		switch {
-		case errors.Is(err, sql.ErrNoRows):
-			return ErrEditConflict
-		default:
+	err := withTx(ctx, m.DB, func(tx *sql.Tx) error {
+		// Check for edit conflicts
+		span.statement(query, args...)
+		err := tx.QueryRowContext(ctx, query, args...).Scan(&forum.Version)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrEditConflict
+			}
 			return err
 		}
-	}
-	return nil
+		return recordChange(ctx, tx, forum.ID, forum.PublicID, ChangeUpdated)
+	})
+	endQuery(span, rowsFor(err), err)
+	return err
+}
+
+// Renew() pushes the expiry of a Forum out by a year. The year is added
+// to whichever is later of the current expiry and now, so renewing an
+// expired listing also makes it visible again
+func (m ForumModel) Renew(ctx context.Context, forum *Forum) error {
+	ctx, span := startQuery(ctx, "ForumModel.Renew")
+	query := `
+		UPDATE forums
+		SET expires_at = GREATEST(expires_at, $3) + INTERVAL '1 year',
+			version = version + 1
+		WHERE id = $1
+		AND version = $2
+		RETURNING expires_at, version
+	`
+	// Create a context
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+	err := withTx(ctx, m.DB, func(tx *sql.Tx) error {
+		// Check for edit conflicts
+		span.statement(query, forum.ID, forum.Version, m.now())
+		err := tx.QueryRowContext(ctx, query, forum.ID, forum.Version, m.now()).Scan(&forum.ExpiresAt, &forum.Version)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrEditConflict
+			}
+			return err
+		}
+		return recordChange(ctx, tx, forum.ID, forum.PublicID, ChangeUpdated)
+	})
+	endQuery(span, rowsFor(err), err)
+	return err
 }
 
-// Delete() removes a specific Forum
-func (m ForumModel) Delete(id int64) error {
+// An Impact reports the rows an operation changes in each table. Changed
+// is false when the operation was only previewed
+type Impact struct {
+	Changed bool             `json:"changed"`
+	Deleted map[string]int64 `json:"deleted"`
+	Created map[string]int64 `json:"created"`
+}
+
+// errDryRun makes withTx() roll back a previewed operation
+var errDryRun = errors.New("dry run")
+
+// Delete() removes a specific Forum and reports what went with it. With
+// dryRun set the same statements run but the transaction is rolled back,
+// so the report says exactly what a real delete would do
+func (m ForumModel) Delete(ctx context.Context, id int64, dryRun bool) (Impact, error) {
+	impact := Impact{Deleted: map[string]int64{}, Created: map[string]int64{}}
 	// Ensure that there is a valid id
 	if id < 1 {
-		return ErrRecordNotFound
+		return impact, ErrRecordNotFound
 	}
-	// Create the delete query
+	// Create the delete query. The public id goes into the changes feed
 	query := `
 		DELETE FROM forums
 		WHERE id = $1
+		RETURNING public_id
 	`
+	ctx, span := startQuery(ctx, "ForumModel.Delete")
 	// Create a context
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	// Cleanup to prevent memory leaks
 	defer cancel()
-	// Execute the query
-	result, err := m.DB.ExecContext(ctx, query, id)
-	if err != nil {
-		return err
-	}
-	// Check how many rows were affected by the delete operation. We
-	// call the RowsAffected() method on the result variable
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-	// Check if no rows were affected
-	if rowsAffected == 0 {
-		return ErrRecordNotFound
+	err := withTx(ctx, m.DB, func(tx *sql.Tx) error {
+		// The FAQs go with the forum through their foreign key, so count
+		// them first. The table is missing when FAQs were never enabled
+		var hasFAQs bool
+		err := tx.QueryRowContext(ctx, `SELECT to_regclass('forum_faqs') IS NOT NULL`).Scan(&hasFAQs)
+		if err != nil {
+			return err
+		}
+		var faqs int64
+		if hasFAQs {
+			err = tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM forum_faqs WHERE forum_id = $1`, id).Scan(&faqs)
+			if err != nil {
+				return err
+			}
+		}
+		// Execute the query
+		span.statement(query, id)
+		var publicID string
+		err = tx.QueryRowContext(ctx, query, id).Scan(&publicID)
+		// No row means there was no forum with that id
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRecordNotFound
+		}
+		if err != nil {
+			return err
+		}
+		impact.Deleted["forums"] = 1
+		if faqs > 0 {
+			impact.Deleted["forum_faqs"] = faqs
+		}
+		err = recordChange(ctx, tx, id, publicID, ChangeDeleted)
+		if err != nil {
+			return err
+		}
+		impact.Created["forum_changes"] = 1
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if errors.Is(err, errDryRun) {
+		err = nil
+	} else {
+		impact.Changed = err == nil
 	}
-	return nil
+	endQuery(span, rowsFor(err), err)
+	return impact, err
 }
 
-// the GetAll() method returns a list of all the schools sorted by id
-func (m ForumModel) GetAll(name string, level string, mode []string, filters Filters) ([]*Forum, Metadata, error) {
-	// Construct the query
-	query := fmt.Sprintf(`
-		SELECT COUNT(*) OVER(), id, created_at, name, level, 
-			   contact, phone, email, website, 
-			   address, mode, version
-		FROM forums
-		WHERE (to_tsvector('simple', name) @@ plainto_tsquery('simple', $1) OR $1 = '')
-		AND (to_tsvector('simple', level) @@ plainto_tsquery('simple', $2) OR $2 = '')
-		AND (mode @> $3 OR $3 = '{}')
-		ORDER BY %s %s, id ASC
-		LIMIT $4 OFFSET $5`, filters.sortColumn(), filters.sortOrder())
-
-	// Create a 3-seconds-timeout context
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+// the GetAll() method returns a list of all the unexpired schools that
+// match the filter, or the expired ones when filter.Expired is set
+func (m ForumModel) GetAll(ctx context.Context, filter ForumFilter, filters Filters) ([]*Forum, Metadata, error) {
+	ctx, span := startQuery(ctx, "ForumModel.GetAll")
+	query, args := m.listQuery(filter, filters, m.now())
+	parent := ctx
+	ctx, tx, cancel, err := m.listTx(ctx)
+	if err != nil {
+		endQuery(span, 0, err)
+		return nil, Metadata{}, err
+	}
 	defer cancel()
+	defer tx.Rollback()
 	// Execute the query
-	args := []interface{}{name, level, pq.Array(mode), filters.limit(), filters.offset()}
-	rows, err := m.DB.QueryContext(ctx, query, args...)
+	span.statement(query, args...)
+	rows, err := tx.QueryContext(ctx, query, args...)
 	if err != nil {
+		err = queryTimeout(parent, err)
+		endQuery(span, 0, err)
 		return nil, Metadata{}, err
 	}
 	// Close the resultset
@@ -233,33 +629,179 @@ func (m ForumModel) GetAll(name string, level string, mode []string, filters Fil
 	forums := []*Forum{}
 	// Iterate over the rows in the resultset
 	for rows.Next() {
-		var forum Forum
+		extra := []interface{}{&totalRecords}
+		var rank *Float
+		var headline *string
+		if filter.Name != "" {
+			rank, headline = new(Float), new(string)
+			extra = append(extra, rank, headline)
+		}
 		// Scan the values from the row into the forum
-		err := rows.Scan(
-			&totalRecords,
-			&forum.ID,
-			&forum.CreatedAt,
-			&forum.Name,
-			&forum.Level,
-			&forum.Contact,
-			&forum.Phone,
-			&forum.Email,
-			&forum.Website,
-			&forum.Address,
-			pq.Array(&forum.Mode),
-			&forum.Version,
-		)
+		forum, err := scanForum(rows, extra...)
 		if err != nil {
+			endQuery(span, len(forums), err)
 			return nil, Metadata{}, err
 		}
+		forum.Rank, forum.Headline = rank, headline
 		// Add the Forum to our slice
-		forums = append(forums, &forum)
+		forums = append(forums, forum)
 	}
 	// Check for errors after looping through the resultset
 	if err = rows.Err(); err != nil {
+		err = queryTimeout(parent, err)
+		endQuery(span, len(forums), err)
 		return nil, Metadata{}, err
 	}
+	endQuery(span, len(forums), nil)
 	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
 	// Return the slice of Forums
 	return forums, metadata, nil
 }
+
+// The listQuery() method builds the GetAll() query and its arguments as
+// of now. The ORDER BY always ends on a unique key, so forums that tie on
+// every sort key still come back in the same order on every page
+func (m ForumModel) listQuery(filter ForumFilter, filters Filters, now time.Time) (string, []interface{}) {
+	b := &queryBuilder{}
+	filter.apply(b, now, "")
+	// Only search queries pay for the rank and headline expressions.
+	// The name is HTML-escaped before ts_headline() adds the highlight tags
+	searchColumns := ""
+	if filter.Name != "" {
+		name := b.arg(filter.Name)
+		searchColumns = fmt.Sprintf(`,
+			   ts_rank(to_tsvector('simple_unaccent', name), plainto_tsquery('simple_unaccent', %s)),
+			   ts_headline('simple_unaccent',
+				   replace(replace(replace(replace(name, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'),
+				   plainto_tsquery('simple_unaccent', %s), %s)`, name, name, b.arg(m.Highlight.options()))
+	}
+	// Construct the query
+	query := fmt.Sprintf(`
+		SELECT %s, COUNT(*) OVER()%s
+		FROM forums
+		%s
+		ORDER BY %s
+		LIMIT %s OFFSET %s`, forumColumns, searchColumns, b.whereClause(), filters.orderBy(b, now), b.arg(filters.limit()), b.arg(filters.offset()))
+	return query, b.args
+}
+
+// The listTx() method starts the read-only transaction a listing or
+// search runs in, with the server's statement timeout set to
+// ListTimeout. The server cancels the query at that timeout. Our own
+// deadline on the returned context is a little later and only catches a
+// server that is stuck. Roll the transaction back and call cancel when
+// done
+func (m ForumModel) listTx(ctx context.Context) (context.Context, *sql.Tx, context.CancelFunc, error) {
+	timeout := m.ListTimeout
+	if timeout <= 0 {
+		timeout = DefaultListTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout+time.Second)
+	// SET LOCAL only lasts for a transaction, so the timeout never leaks
+	// onto the next query to use this connection
+	tx, err := m.dbFor(true).BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		cancel()
+		return nil, nil, nil, err
+	}
+	_, err = tx.ExecContext(ctx, `SELECT set_config('statement_timeout', $1, true)`, strconv.FormatInt(timeout.Milliseconds(), 10))
+	if err != nil {
+		tx.Rollback()
+		cancel()
+		return nil, nil, nil, err
+	}
+	return ctx, tx, cancel, nil
+}
+
+// The queryTimeout() function turns a query cancelled by our own limits
+// into ErrQueryTimeout. When the caller's context is done the client went
+// away, so that error is returned unchanged
+func queryTimeout(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return err
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "57014" {
+		return ErrQueryTimeout
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrQueryTimeout
+	}
+	return err
+}
+
+// The dimensions the forum listing can be faceted on, and the most values
+// returned for each
+var FacetDimensions = []string{"level", "mode", "languages"}
+
+const maxFacetValues = 50
+
+// Facets() counts the listed forums for each value of the requested
+// dimensions. Each count applies every filter except the one on its own
+// dimension, so a client can see how many results picking another value
+// would give. Modes and languages are unnested so that a forum counts
+// towards each of its values
+func (m ForumModel) Facets(ctx context.Context, filter ForumFilter, dimensions []string) (map[string]map[string]int, error) {
+	facets := make(map[string]map[string]int, len(dimensions))
+	for _, dimension := range dimensions {
+		// The column grouped on and where its values come from
+		var value, from string
+		switch dimension {
+		case "level":
+			value, from = "level", "forums"
+		case "mode":
+			value, from = "m", "forums, unnest(mode) AS m"
+		case "languages":
+			value, from = "l", "forums, unnest(languages) AS l"
+		default:
+			return nil, fmt.Errorf("unknown facet dimension %q", dimension)
+		}
+		b := &queryBuilder{}
+		filter.apply(b, m.now(), dimension)
+		query := fmt.Sprintf(`
+			SELECT %[1]s, COUNT(*)
+			FROM %[2]s
+			%[3]s
+			GROUP BY %[1]s
+			ORDER BY COUNT(*) DESC, %[1]s
+			LIMIT %[4]s`, value, from, b.whereClause(), b.arg(maxFacetValues))
+		counts, err := m.facet(ctx, "ForumModel.Facets."+dimension, query, b.args...)
+		if err != nil {
+			return nil, err
+		}
+		facets[dimension] = counts
+	}
+	return facets, nil
+}
+
+// The facet() method runs one grouped facet query
+func (m ForumModel) facet(ctx context.Context, spanName string, query string, args ...interface{}) (map[string]int, error) {
+	ctx, span := startQuery(ctx, spanName)
+	// Create a context
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+	span.statement(query, args...)
+	rows, err := m.dbFor(true).QueryContext(ctx, query, args...)
+	if err != nil {
+		endQuery(span, 0, err)
+		return nil, err
+	}
+	defer rows.Close()
+	counts := make(map[string]int)
+	for rows.Next() {
+		var value string
+		var count int
+		if err := rows.Scan(&value, &count); err != nil {
+			endQuery(span, len(counts), err)
+			return nil, err
+		}
+		counts[value] = count
+	}
+	err = rows.Err()
+	endQuery(span, len(counts), err)
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
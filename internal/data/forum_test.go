@@ -0,0 +1,174 @@
+// Filename: internal/data/forum_test.go
+
+package data
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"maps"
+	"math/rand"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The cap and an empty list are answered without a query, so the model
+// needs no database here
+func TestGetManyLimits(t *testing.T) {
+	var m ForumModel
+	ids := make([]int64, MaxGetMany+1)
+	if _, _, err := m.GetMany(context.Background(), ids); !errors.Is(err, ErrTooManyIDs) {
+		t.Errorf("got error %v for %d ids; want ErrTooManyIDs", err, len(ids))
+	}
+	forums, missing, err := m.GetMany(context.Background(), nil)
+	if err != nil || forums == nil || len(forums) != 0 || missing == nil || len(missing) != 0 {
+		t.Errorf("got %v, %v, %v for no ids; want two empty lists", forums, missing, err)
+	}
+}
+
+// Forums come back in the order asked for, a repeated id only at its
+// first position, with the ids not found listed in the same order
+func TestInIDOrder(t *testing.T) {
+	found := map[int64]*Forum{
+		3: {ID: 3},
+		7: {ID: 7},
+		9: {ID: 9},
+	}
+	forums, missing := inIDOrder([]int64{9, 4, 3, 9, 8, 7, 4}, found)
+	var got []int64
+	for _, forum := range forums {
+		got = append(got, forum.ID)
+	}
+	if want := []int64{9, 3, 7}; !slices.Equal(got, want) {
+		t.Errorf("got forums %v; want %v", got, want)
+	}
+	if want := []int64{4, 8}; !slices.Equal(missing, want) {
+		t.Errorf("got missing %v; want %v", missing, want)
+	}
+
+	forums, missing = inIDOrder([]int64{1}, map[int64]*Forum{})
+	if len(forums) != 0 || !slices.Equal(missing, []int64{1}) {
+		t.Errorf("got %v, %v with nothing found; want the id missing", forums, missing)
+	}
+}
+
+// The ORDER BY of a listing query
+var orderByRX = regexp.MustCompile(`ORDER BY (.+)\n`)
+
+// The sortForums() function orders forums the way the database runs
+// clause, for the columns the tests sort on
+func sortForums(t *testing.T, forums []*Forum, clause string) {
+	t.Helper()
+	keys := strings.Split(clause, ", ")
+	slices.SortStableFunc(forums, func(a, b *Forum) int {
+		for _, key := range keys {
+			column, order, _ := strings.Cut(key, " ")
+			var c int
+			switch column {
+			case "id":
+				c = cmp.Compare(a.ID, b.ID)
+			case "level":
+				c = cmp.Compare(a.Level, b.Level)
+			case "name_sort":
+				c = cmp.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+			default:
+				t.Fatalf("can't sort on %q", column)
+			}
+			if order == "DESC" {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+}
+
+// Forums that tie on every sort key the client asked for still come
+// back in one order, however the rows happen to be read, and building the
+// query again gives the same statement
+func TestListQueryTies(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	filters := Filters{Page: 1, PageSize: 20, Sort: []string{"level", "-name"}, SortList: []string{"id", "name", "level", "-id", "-name", "-level"}}
+	query, args := ForumModel{}.listQuery(ForumFilter{}, filters, now)
+	again, againArgs := ForumModel{}.listQuery(ForumFilter{}, filters, now)
+	if query != again || !slices.Equal(args, againArgs) {
+		t.Fatalf("got a different query the second time:\n%s\n%v\n%s\n%v", query, args, again, againArgs)
+	}
+	match := orderByRX.FindStringSubmatch(query)
+	if match == nil || match[1] != "level ASC, name_sort DESC, id ASC" {
+		t.Fatalf("got ORDER BY %q; want it to end on the id", match)
+	}
+
+	forums := []*Forum{}
+	for id := int64(1); id <= 8; id++ {
+		forums = append(forums, &Forum{ID: id, Level: "secondary", Name: "Study Group"})
+	}
+	forums = append(forums, &Forum{ID: 9, Level: "primary", Name: "Study Group"})
+	var want []int64
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 20; i++ {
+		rows := slices.Clone(forums)
+		r.Shuffle(len(rows), func(i, j int) { rows[i], rows[j] = rows[j], rows[i] })
+		sortForums(t, rows, match[1])
+		ids := []int64{}
+		for _, forum := range rows {
+			ids = append(ids, forum.ID)
+		}
+		if want == nil {
+			want = ids
+		} else if !slices.Equal(ids, want) {
+			t.Fatalf("got order %v; want %v every time", ids, want)
+		}
+	}
+	if !slices.Equal(want, []int64{9, 1, 2, 3, 4, 5, 6, 7, 8}) {
+		t.Errorf("got order %v; want the primary forum first and the ties by id", want)
+	}
+}
+
+// A dry run reports what a delete would do and leaves every table as it
+// was. The real delete then does exactly what was reported
+func TestDeleteDryRun(t *testing.T) {
+	db := &memDB{}
+	db.load(fixtures(t))
+	forum := fixture(t, "secondary")
+	db.tables.faqs[forum.ID] = 3
+	before := db.tables.clone()
+	m := ForumModel{DB: db.open(t)}
+
+	preview, err := m.Delete(context.Background(), forum.ID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preview.Changed || !maps.Equal(preview.Deleted, map[string]int64{"forums": 1, "forum_faqs": 3}) || !maps.Equal(preview.Created, map[string]int64{"forum_changes": 1}) {
+		t.Errorf("got preview %+v; want the forum, its 3 FAQs and one change, unchanged", preview)
+	}
+	if db.commits != 0 || db.rollbacks != 1 {
+		t.Errorf("got %d commits and %d rollbacks; want the one rollback", db.commits, db.rollbacks)
+	}
+	if !maps.Equal(db.tables.forums, before.forums) || !maps.Equal(db.tables.faqs, before.faqs) || db.tables.changes != before.changes {
+		t.Errorf("got tables %+v after the dry run; want %+v", db.tables, before)
+	}
+
+	impact, err := m.Delete(context.Background(), forum.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !impact.Changed || !maps.Equal(impact.Deleted, preview.Deleted) || !maps.Equal(impact.Created, preview.Created) {
+		t.Errorf("got impact %+v; want the previewed %+v", impact, preview)
+	}
+	if _, ok := db.tables.forums[forum.ID]; ok || db.tables.faqs[forum.ID] != 0 || db.tables.changes != 1 {
+		t.Errorf("got tables %+v after the delete; want forum %d and its FAQs gone", db.tables, forum.ID)
+	}
+	if len(db.tables.forums) != len(before.forums)-1 {
+		t.Errorf("got %d forums left; want only the one deleted", len(db.tables.forums))
+	}
+
+	if _, err := m.Delete(context.Background(), 99, true); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("got %v previewing a missing forum; want ErrRecordNotFound", err)
+	}
+}
@@ -0,0 +1,84 @@
+// Filename: internal/data/imports.go
+
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Define an ImportModel which wraps a sql.DB connection pool. It holds
+// the queries that only the registry importer needs
+type ImportModel struct {
+	DB    *sql.DB
+	Clock Clock
+}
+
+// Refs() returns the ids of the imported forums keyed by their external
+// reference. It reads the primary so a run never works from a stale copy
+func (m ImportModel) Refs(ctx context.Context) (map[string]int64, error) {
+	ctx, span := startQuery(ctx, "ImportModel.Refs")
+	query := `
+		SELECT external_ref, id
+		FROM forums
+		WHERE external_ref IS NOT NULL
+	`
+	// Create a context
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+	span.statement(query)
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		endQuery(span, 0, err)
+		return nil, err
+	}
+	defer rows.Close()
+	refs := make(map[string]int64)
+	for rows.Next() {
+		var ref string
+		var id int64
+		if err := rows.Scan(&ref, &id); err != nil {
+			endQuery(span, len(refs), err)
+			return nil, err
+		}
+		refs[ref] = id
+	}
+	err = rows.Err()
+	endQuery(span, len(refs), err)
+	if err != nil {
+		return nil, err
+	}
+	return refs, nil
+}
+
+// Flag() marks the imported forums whose reference is not in present as
+// missing from the registry and clears the mark on those that are. A
+// forum keeps the time it was first found missing. It returns how many
+// forums are flagged once it is done
+func (m ImportModel) Flag(ctx context.Context, present []string) (int64, error) {
+	ctx, span := startQuery(ctx, "ImportModel.Flag")
+	query := `
+		WITH marked AS (
+			UPDATE forums
+			SET import_missing_since = CASE
+				WHEN external_ref = ANY($1) THEN NULL
+				ELSE COALESCE(import_missing_since, $2)
+			END
+			WHERE external_ref IS NOT NULL
+			RETURNING import_missing_since
+		)
+		SELECT COUNT(*) FROM marked WHERE import_missing_since IS NOT NULL
+	`
+	// Create a context
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+	var flagged int64
+	now := m.now()
+	span.statement(query, present, now)
+	err := m.DB.QueryRowContext(ctx, query, present, now).Scan(&flagged)
+	endQuery(span, rowsFor(err), err)
+	return flagged, err
+}
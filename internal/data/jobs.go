@@ -0,0 +1,80 @@
+// Filename: internal/data/jobs.go
+
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// The kinds of job recorded in admin_jobs
+const (
+	JobImport = "import"
+)
+
+// An AdminJob is the record of one run of a background job. FinishedAt is
+// null while the job is running. Summary is whatever the job reports
+// about its run
+type AdminJob struct {
+	ID         int64       `json:"id"`
+	Kind       string      `json:"kind"`
+	StartedAt  time.Time   `json:"started_at"`
+	FinishedAt NullTime    `json:"finished_at"`
+	Summary    interface{} `json:"summary"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// Define a JobModel which wraps a sql.DB connection pool
+type JobModel struct {
+	DB    *sql.DB
+	Clock Clock
+}
+
+// Start() records a job that is starting and fills in its id
+func (m JobModel) Start(ctx context.Context, job *AdminJob) error {
+	ctx, span := startQuery(ctx, "JobModel.Start")
+	query := `
+		INSERT INTO admin_jobs (kind, started_at)
+		VALUES ($1, $2)
+		RETURNING id
+	`
+	// Create a context
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+	span.statement(query, job.Kind, job.StartedAt)
+	err := m.DB.QueryRowContext(ctx, query, job.Kind, job.StartedAt).Scan(&job.ID)
+	endQuery(span, rowsFor(err), err)
+	return err
+}
+
+// Finish() records the outcome of a started job
+func (m JobModel) Finish(ctx context.Context, job *AdminJob) error {
+	ctx, span := startQuery(ctx, "JobModel.Finish")
+	query := `
+		UPDATE admin_jobs
+		SET finished_at = $4, summary = $1, error = $2
+		WHERE id = $3
+		RETURNING finished_at
+	`
+	summary, err := json.Marshal(job.Summary)
+	if err != nil {
+		endQuery(span, 0, err)
+		return err
+	}
+	// Create a context
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+	now := m.now()
+	span.statement(query, summary, job.Error, job.ID, now)
+	err = m.DB.QueryRowContext(ctx, query, summary, job.Error, job.ID, now).Scan(&job.FinishedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrRecordNotFound
+	}
+	endQuery(span, rowsFor(err), err)
+	return err
+}
@@ -0,0 +1,23 @@
+// Filename: internal/data/languages.go
+
+package data
+
+// The languages a forum may teach in: ISO 639-1 codes where there is one
+// and ISO 639-3 for Belizean Kriol, Mopan and Q'eqchi'
+var LanguageList = []string{"en", "es", "bzj", "mop", "kek"}
+
+// Forums teach in English unless they say otherwise
+var DefaultLanguages = Languages{"en"}
+
+// Languages holds the languages a forum teaches in. It is decoded the
+// same way as Modes, so the comma-separated form works here too
+type Languages []string
+
+func (l *Languages) UnmarshalJSON(js []byte) error {
+	list, err := decodeStringList(js, "languages")
+	if err != nil || list == nil {
+		return err
+	}
+	*l = list
+	return nil
+}
@@ -0,0 +1,142 @@
+// Filename: internal/data/memdb_test.go
+
+package data
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"maps"
+	"strings"
+	"testing"
+)
+
+// memTables is the data held by a memDB: the public id of each forum,
+// the number of FAQs each forum has and how many changes were recorded
+type memTables struct {
+	forums  map[int64]string
+	faqs    map[int64]int64
+	changes int
+}
+
+// The clone() method copies the tables for a transaction to work on
+func (t memTables) clone() memTables {
+	return memTables{forums: maps.Clone(t.forums), faqs: maps.Clone(t.faqs), changes: t.changes}
+}
+
+// A memDB is a database/sql driver over memTables that answers the
+// statements ForumModel.Delete() runs. A transaction works on a copy that
+// only replaces the tables when it commits, so a test can see whether a
+// run left the database as it was
+type memDB struct {
+	tables    memTables
+	commits   int
+	rollbacks int
+}
+
+// The open() method returns a pool whose connections all share db
+func (db *memDB) open(t *testing.T) *sql.DB {
+	pool := sql.OpenDB(memConnector{db})
+	t.Cleanup(func() { pool.Close() })
+	return pool
+}
+
+type memConnector struct{ db *memDB }
+
+func (c memConnector) Connect(context.Context) (driver.Conn, error) {
+	return &memConn{db: c.db}, nil
+}
+
+func (c memConnector) Driver() driver.Driver {
+	return nil
+}
+
+// A memConn runs statements against the open transaction's tables
+type memConn struct {
+	db *memDB
+	tx *memTables
+}
+
+var errMemStatement = errors.New("memdb: unsupported statement")
+
+func (c *memConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errMemStatement
+}
+
+func (c *memConn) Close() error {
+	return nil
+}
+
+func (c *memConn) Begin() (driver.Tx, error) {
+	tables := c.db.tables.clone()
+	c.tx = &tables
+	return c, nil
+}
+
+func (c *memConn) Commit() error {
+	c.db.tables = *c.tx
+	c.db.commits++
+	c.tx = nil
+	return nil
+}
+
+func (c *memConn) Rollback() error {
+	c.db.rollbacks++
+	c.tx = nil
+	return nil
+}
+
+func (c *memConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if c.tx == nil {
+		return nil, errMemStatement
+	}
+	switch {
+	case strings.Contains(query, "to_regclass('forum_faqs')"):
+		return &memRows{values: []driver.Value{true}}, nil
+	case strings.Contains(query, "COUNT(*) FROM forum_faqs"):
+		return &memRows{values: []driver.Value{c.tx.faqs[args[0].Value.(int64)]}}, nil
+	case strings.Contains(query, "DELETE FROM forums"):
+		id := args[0].Value.(int64)
+		publicID, ok := c.tx.forums[id]
+		if !ok {
+			return &memRows{}, nil
+		}
+		delete(c.tx.forums, id)
+		delete(c.tx.faqs, id)
+		return &memRows{values: []driver.Value{publicID}}, nil
+	}
+	return nil, errMemStatement
+}
+
+func (c *memConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if c.tx == nil || !strings.Contains(query, "INSERT INTO forum_changes") {
+		return nil, errMemStatement
+	}
+	c.tx.changes++
+	return driver.RowsAffected(1), nil
+}
+
+// memRows holds at most one row
+type memRows struct {
+	values []driver.Value
+	done   bool
+}
+
+func (r *memRows) Columns() []string {
+	return make([]string, len(r.values))
+}
+
+func (r *memRows) Close() error {
+	return nil
+}
+
+func (r *memRows) Next(dest []driver.Value) error {
+	if r.done || r.values == nil {
+		return io.EOF
+	}
+	r.done = true
+	copy(dest, r.values)
+	return nil
+}
@@ -0,0 +1,172 @@
+// Filename: internal/data/metrics.go
+
+package data
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"expvar"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// QueryMetrics holds a timing histogram for each model method, keyed by
+// name (e.g. "ForumModel.Get")
+var QueryMetrics = expvar.NewMap("db_queries")
+
+// The upper bounds of the histogram buckets in milliseconds. Like a
+// Prometheus histogram the counts are cumulative and a final +Inf bucket
+// holds everything
+var queryBuckets = []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000}
+
+// The histogram type counts query durations into queryBuckets
+type histogram struct {
+	mu      sync.Mutex
+	buckets []int64
+	count   int64
+	sumMS   float64
+}
+
+func (h *histogram) observe(d time.Duration) {
+	ms := float64(d) / float64(time.Millisecond)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.buckets == nil {
+		h.buckets = make([]int64, len(queryBuckets))
+	}
+	for i, bound := range queryBuckets {
+		if ms <= bound {
+			h.buckets[i]++
+		}
+	}
+	h.count++
+	h.sumMS += ms
+}
+
+// String() renders the histogram as JSON so it can be served by expvar
+func (h *histogram) String() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var b strings.Builder
+	b.WriteString(`{"buckets": {`)
+	for i, bound := range queryBuckets {
+		var n int64
+		if h.buckets != nil {
+			n = h.buckets[i]
+		}
+		fmt.Fprintf(&b, `"%g": %d, `, bound, n)
+	}
+	fmt.Fprintf(&b, `"+Inf": %d}, "count": %d, "sum_ms": %.3f}`, h.count, h.count, h.sumMS)
+	return b.String()
+}
+
+// histogramsMu stops two first calls for a name creating two histograms
+var histogramsMu sync.Mutex
+
+// The histogramFor() function returns the histogram for a query name,
+// creating it on first use
+func histogramFor(name string) *histogram {
+	if h, ok := QueryMetrics.Get(name).(*histogram); ok {
+		return h
+	}
+	histogramsMu.Lock()
+	defer histogramsMu.Unlock()
+	if h, ok := QueryMetrics.Get(name).(*histogram); ok {
+		return h
+	}
+	h := &histogram{}
+	QueryMetrics.Set(name, h)
+	return h
+}
+
+// The query log settings. Nothing is logged until LogSlowQueries() is
+// called
+var slowQueries struct {
+	logger    *log.Logger
+	threshold time.Duration
+}
+
+// LogSlowQueries() logs every query that takes longer than threshold, and
+// every query that fails, as a warning. Argument values are never logged
+// since they may hold contact details, only their types and lengths. A
+// threshold of zero logs failures only
+func LogSlowQueries(logger *log.Logger, threshold time.Duration) {
+	slowQueries.logger = logger
+	slowQueries.threshold = threshold
+}
+
+// The recordQuery() function adds a finished query to the metrics and the
+// query log. Expected outcomes such as a missing record aren't failures
+func recordQuery(span *querySpan, d time.Duration, rows int, err error) {
+	histogramFor(span.name).observe(d)
+	if slowQueries.logger == nil {
+		return
+	}
+	failed := err != nil && !expectedError(err)
+	slow := slowQueries.threshold > 0 && d > slowQueries.threshold
+	if !failed && !slow {
+		return
+	}
+	outcome := "slow query"
+	if failed {
+		outcome = "query failed"
+	}
+	line := fmt.Sprintf("WARNING: %s name=%s fingerprint=%s duration=%s rows=%d args=[%s]",
+		outcome, span.name, fingerprint(span.query), d.Round(time.Millisecond), rows, describeArgs(span.args))
+	if failed {
+		line += " error=" + errorClass(err)
+	}
+	slowQueries.logger.Print(line)
+}
+
+// The fingerprint() function returns a short hash of a statement with its
+// whitespace normalised, so the same statement always logs the same value
+// however it was indented
+func fingerprint(query string) string {
+	if query == "" {
+		return "none"
+	}
+	sum := sha256.Sum256([]byte(strings.Join(strings.Fields(query), " ")))
+	return hex.EncodeToString(sum[:6])
+}
+
+// The describeArgs() function lists the type of each argument, with the
+// length of strings, byte slices and lists, e.g. "int64, string(12)"
+func describeArgs(args []interface{}) string {
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		if arg == nil {
+			parts[i] = "nil"
+			continue
+		}
+		v := reflect.ValueOf(arg)
+		switch v.Kind() {
+		case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+			parts[i] = fmt.Sprintf("%T(%d)", arg, v.Len())
+		default:
+			parts[i] = fmt.Sprintf("%T", arg)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// The errorClass() function describes an error without the input values
+// that PostgreSQL sometimes quotes in its messages. Database errors are
+// logged by their SQLSTATE code and the constraint involved
+func errorClass(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		if pgErr.ConstraintName != "" {
+			return fmt.Sprintf("sqlstate:%s constraint=%s", pgErr.Code, pgErr.ConstraintName)
+		}
+		return "sqlstate:" + pgErr.Code
+	}
+	return fmt.Sprintf("%q", err.Error())
+}
@@ -0,0 +1,112 @@
+// Filename: internal/data/metrics_test.go
+
+package data
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// The same statement fingerprints the same however it is laid out
+func TestFingerprint(t *testing.T) {
+	a := fingerprint("SELECT id\n\t\tFROM forums\n\t\tWHERE id = $1")
+	b := fingerprint("  SELECT id FROM forums WHERE id = $1  ")
+	if a != b {
+		t.Errorf("got %s and %s for the same statement", a, b)
+	}
+	if len(a) != 12 {
+		t.Errorf("got fingerprint %q; want 12 hex characters", a)
+	}
+	if c := fingerprint("SELECT id FROM forums WHERE id = $2"); c == a {
+		t.Error("two statements share a fingerprint")
+	}
+	if got := fingerprint(""); got != "none" {
+		t.Errorf("got %q for no statement; want \"none\"", got)
+	}
+}
+
+func TestDescribeArgs(t *testing.T) {
+	capacity := int32(30)
+	got := describeArgs([]interface{}{int64(7), "ana@example.com", nil, []string{"online", "evening"}, &capacity, []byte("abc"), true})
+	want := "int64, string(15), nil, []string(2), *int32, []uint8(3), bool"
+	if got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+	if got := describeArgs(nil); got != "" {
+		t.Errorf("got %q for no args; want \"\"", got)
+	}
+}
+
+// A database error is logged by its code, never by its message, which
+// can quote the values that broke it
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"constraint", &pgconn.PgError{Code: "23505", Message: `duplicate key value (email)=(ana@example.com)`, ConstraintName: "forums_email_key"}, "sqlstate:23505 constraint=forums_email_key"},
+		{"code only", fmt.Errorf("insert: %w", &pgconn.PgError{Code: "57014", Message: "canceling statement"}), "sqlstate:57014"},
+		{"other", errors.New("connection reset"), `"connection reset"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errorClass(tt.err); got != tt.want {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// Slow and failed queries are logged without their argument values.
+// Quick ones and expected outcomes are not logged at all
+func TestRecordQueryLog(t *testing.T) {
+	var buf bytes.Buffer
+	LogSlowQueries(log.New(&buf, "", 0), 100*time.Millisecond)
+	t.Cleanup(func() { LogSlowQueries(nil, 0) })
+
+	record := func(d time.Duration, err error) string {
+		buf.Reset()
+		_, span := startQuery(context.Background(), "MetricsTest.Get")
+		span.statement("SELECT * FROM forums WHERE email = $1", "ana@example.com")
+		recordQuery(span, d, 1, err)
+		return buf.String()
+	}
+
+	if got := record(10*time.Millisecond, nil); got != "" {
+		t.Errorf("logged a quick query: %q", got)
+	}
+	if got := record(10*time.Millisecond, ErrRecordNotFound); got != "" {
+		t.Errorf("logged a missing record: %q", got)
+	}
+	got := record(250*time.Millisecond, nil)
+	for _, want := range []string{"WARNING: slow query", "name=MetricsTest.Get", "fingerprint=" + fingerprint("SELECT * FROM forums WHERE email = $1"), "duration=250ms", "rows=1", "args=[string(15)]"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("got %q; want it to contain %q", got, want)
+		}
+	}
+	got = record(time.Millisecond, &pgconn.PgError{Code: "23505", Message: "ana@example.com", ConstraintName: "forums_email_key"})
+	if !strings.Contains(got, "WARNING: query failed") || !strings.Contains(got, "error=sqlstate:23505 constraint=forums_email_key") {
+		t.Errorf("got %q; want the failure and its code", got)
+	}
+	if strings.Contains(got, "ana@example.com") {
+		t.Errorf("logged an argument value: %q", got)
+	}
+
+	// A threshold of zero only logs failures
+	LogSlowQueries(log.New(&buf, "", 0), 0)
+	if got := record(time.Hour, nil); got != "" {
+		t.Errorf("logged a slow query with no threshold: %q", got)
+	}
+	if h, ok := QueryMetrics.Get("MetricsTest.Get").(*histogram); !ok || h.count != 5 {
+		t.Error("the queries were not all counted in the histogram")
+	}
+}
@@ -3,6 +3,7 @@
 package data
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 )
@@ -10,16 +11,53 @@ import (
 var (
 	ErrRecordNotFound = errors.New("record not found")
 	ErrEditConflict   = errors.New("edit conflict")
+	ErrQueryTimeout   = errors.New("query timeout")
 )
 
 // A wrapper for our data models
 type Models struct {
-	Forums ForumModel
+	Forums  ForumModel
+	Changes ChangeModel
+	Views   ViewModel
+	FAQs    FAQModel
+	Imports ImportModel
+	Jobs    JobModel
+	Tables  TableModel
 }
 
-// NewModels() allows us to create a new Models
-func NewModels(db *sql.DB) Models {
+// NewModels() allows us to create a new Models. readDB is the replica
+// pool, or nil to run everything against the primary. The changes feed
+// always reads the primary since its settle window assumes no replica lag
+func NewModels(db *sql.DB, readDB *sql.DB) Models {
 	return Models{
-		Forums: ForumModel{DB: db},
+		Forums:  ForumModel{DB: db, ReadDB: readDB},
+		Changes: ChangeModel{DB: db},
+		Views:   ViewModel{DB: db, ReadDB: readDB},
+		FAQs:    FAQModel{DB: db, ReadDB: readDB},
+		Imports: ImportModel{DB: db},
+		Jobs:    JobModel{DB: db},
+		Tables:  TableModel{DB: db},
 	}
 }
+
+// SetClock() makes every model that tells the time read clock
+func (m *Models) SetClock(clock Clock) {
+	m.Forums.Clock = clock
+	m.Changes.Clock = clock
+	m.Imports.Clock = clock
+	m.Jobs.Clock = clock
+}
+
+// The withTx() function runs fn inside a transaction. The transaction is
+// committed when fn succeeds and rolled back when it returns an error
+func withTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,63 @@
+// Filename: internal/data/modes.go
+
+package data
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// Modes holds the delivery modes of a forum. It is always written out as
+// a JSON array but also accepts the comma-separated string ("online,
+// evening") posted by the older intake form
+type Modes []string
+
+// UnmarshalJSON() accepts either an array of strings or a single string
+// which is split on commas. Entries are trimmed and lowercased and empty
+// entries from the string form are dropped. Duplicates are kept so that
+// validation can report them. Either form stops at MaxInputEntries
+func (m *Modes) UnmarshalJSON(js []byte) error {
+	list, err := decodeStringList(js, "mode")
+	if err != nil || list == nil {
+		return err
+	}
+	*m = list
+	return nil
+}
+
+// The decodeStringList() function does the decoding for the list fields
+// that accept both forms. It returns nil for a JSON null so that, like
+// the standard decoder, the destination is left untouched
+func decodeStringList(js []byte, field string) ([]string, error) {
+	if string(js) == "null" {
+		return nil, nil
+	}
+	list, ok, err := boundedStrings(js, field)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		values := make([]string, 0, len(list))
+		for _, value := range list {
+			values = append(values, strings.ToLower(strings.TrimSpace(value)))
+		}
+		return values, nil
+	}
+	var csv string
+	if err := json.Unmarshal(js, &csv); err != nil {
+		return nil, errors.New(field + " must be an array of strings or a comma-separated string")
+	}
+	parts := strings.Split(csv, ",")
+	if len(parts) > MaxInputEntries {
+		return nil, &TooManyEntriesError{Field: field, Limit: MaxInputEntries}
+	}
+	values := []string{}
+	for _, value := range parts {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value != "" {
+			values = append(values, value)
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,93 @@
+// Filename: internal/data/modes_test.go
+
+package data
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestModesUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		js   string
+		want Modes
+		err  string
+	}{
+		{"array", `["online", "evening"]`, Modes{"online", "evening"}, ""},
+		{"array is trimmed and lowercased", `[" Online ", "EVENING"]`, Modes{"online", "evening"}, ""},
+		{"empty array", `[]`, Modes{}, ""},
+		{"string", `"online,evening"`, Modes{"online", "evening"}, ""},
+		{"string is trimmed and lowercased", `" Online , Evening "`, Modes{"online", "evening"}, ""},
+		{"empty entries are dropped", `"online,,evening,"`, Modes{"online", "evening"}, ""},
+		{"empty string", `""`, Modes{}, ""},
+		// Validation reports repeats, so they are kept
+		{"repeats are kept", `"online,online"`, Modes{"online", "online"}, ""},
+		{"number", `12`, nil, "mode must be an array of strings or a comma-separated string"},
+		{"array of numbers", `[1, 2]`, nil, "mode must be an array of strings or a comma-separated string"},
+		{"object", `{"online": true}`, nil, "mode must be an array of strings or a comma-separated string"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var m Modes
+			err := json.Unmarshal([]byte(tt.js), &m)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("got error %v; want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(m, tt.want) || (m == nil) != (tt.want == nil) {
+				t.Errorf("got %#v; want %#v", m, tt.want)
+			}
+		})
+	}
+}
+
+// A null leaves the destination alone, the way the standard decoder does
+func TestModesNull(t *testing.T) {
+	input := struct {
+		Mode      Modes     `json:"mode"`
+		Languages Languages `json:"languages"`
+	}{Mode: Modes{"online"}, Languages: Languages{"es"}}
+	if err := json.Unmarshal([]byte(`{"mode": null, "languages": null}`), &input); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(input.Mode, Modes{"online"}) || !slices.Equal(input.Languages, Languages{"es"}) {
+		t.Errorf("got %v and %v; want the values from before", input.Mode, input.Languages)
+	}
+}
+
+// Modes go out as an array whichever form they came in
+func TestModesMarshalJSON(t *testing.T) {
+	var m Modes
+	if err := json.Unmarshal([]byte(`"online,evening"`), &m); err != nil {
+		t.Fatal(err)
+	}
+	js, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(js), `["online","evening"]`; got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+}
+
+// Languages are decoded the same way, with their own field name in errors
+func TestLanguagesUnmarshalJSON(t *testing.T) {
+	var l Languages
+	if err := json.Unmarshal([]byte(`"EN, es"`), &l); err != nil {
+		t.Fatal(err)
+	}
+	if want := (Languages{"en", "es"}); !slices.Equal(l, want) {
+		t.Errorf("got %v; want %v", l, want)
+	}
+	err := json.Unmarshal([]byte(`true`), &l)
+	if want := "languages must be an array of strings or a comma-separated string"; err == nil || err.Error() != want {
+		t.Errorf("got error %v; want %q", err, want)
+	}
+}
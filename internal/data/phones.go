@@ -0,0 +1,94 @@
+// Filename: internal/data/phones.go
+
+package data
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"AWD_FinalProject.ryanarmstrong.net/internal/validator"
+)
+
+// Phones holds the numbers a forum can be reached on. Only the primary
+// number is required; the extension belongs to it and the alternate is a
+// second line such as a WhatsApp-only number
+type Phones struct {
+	Primary   string `json:"primary,omitempty"`
+	Extension string `json:"extension,omitempty"`
+	Alternate string `json:"alternate,omitempty"`
+}
+
+// An extension is digits only
+var phoneExtRX = regexp.MustCompile(`^[0-9]+$`)
+
+// Normalize() writes the numbers in our one format and drops an "x" or
+// "ext" that was typed in front of the extension
+func (p *Phones) Normalize() {
+	p.Primary = NormalizePhone(p.Primary)
+	p.Alternate = NormalizePhone(p.Alternate)
+	ext := strings.ToLower(strings.TrimSpace(p.Extension))
+	ext = strings.TrimPrefix(ext, "ext")
+	ext = strings.TrimPrefix(ext, "x")
+	ext = strings.TrimPrefix(ext, ".")
+	p.Extension = strings.TrimSpace(ext)
+}
+
+// NormalizePhone() turns a number typed as "(501) 223.4567", "501 223
+// 4567" or in non-ASCII digits into the 501-223-4567 form we store. Only
+// a leading + is kept besides the digits. A number without ten digits
+// can't be put in that form and is returned with just the separators
+// removed, for validation to reject
+func NormalizePhone(phone string) string {
+	phone = strings.TrimSpace(phone)
+	digits := PhoneDigits(phone)
+	if len(digits) != 10 {
+		if digits == "" {
+			return phone
+		}
+		if strings.HasPrefix(phone, "+") {
+			return "+" + digits
+		}
+		return digits
+	}
+	normalized := digits[:3] + "-" + digits[3:6] + "-" + digits[6:]
+	if strings.HasPrefix(phone, "+") {
+		normalized = "+" + normalized
+	}
+	return normalized
+}
+
+// PhoneDigits() returns only the digits of a number, as ASCII
+func PhoneDigits(phone string) string {
+	var b strings.Builder
+	for _, r := range phone {
+		if unicode.IsDigit(r) {
+			b.WriteRune(asciiDigit(r))
+		}
+	}
+	return b.String()
+}
+
+// The asciiDigit() function maps a decimal digit from any script, such
+// as the Arabic-Indic or fullwidth ones, to 0-9. Unicode lays out each
+// script's digits as runs of ten code points starting at zero
+func asciiDigit(r rune) rune {
+	first := r
+	for unicode.IsDigit(first - 1) {
+		first--
+	}
+	return '0' + (r-first)%10
+}
+
+// The validatePhones() function checks the numbers of a forum
+func validatePhones(v *validator.Validator, p Phones) {
+	v.Check(p.Primary != "", "phones.primary", "required")
+	v.Check(validator.Matches(p.Primary, validator.PhoneRX), "phones.primary", "invalid_phone")
+	if p.Extension != "" {
+		v.Check(len(p.Extension) <= ForumPhoneExtRule.MaxLength, "phones.extension", "max_bytes", ForumPhoneExtRule.MaxLength)
+		v.Check(validator.Matches(p.Extension, phoneExtRX), "phones.extension", "digits_only")
+	}
+	if p.Alternate != "" {
+		v.Check(validator.Matches(p.Alternate, validator.PhoneRX), "phones.alternate", "invalid_phone")
+	}
+}
@@ -0,0 +1,69 @@
+// Filename: internal/data/publicid.go
+
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Public ids are 10 lowercase base32 characters, about 50 random bits, so
+// unlike the serial ids they can't be walked
+var PublicIDRX = regexp.MustCompile(`^[a-z2-7]{10}$`)
+
+// How many times Insert() draws a new public id after a collision
+const publicIDAttempts = 3
+
+// The newPublicID() function draws a fresh public id
+func newPublicID() string {
+	return strings.ToLower(rand.Text()[:10])
+}
+
+// The isPublicIDCollision() function reports whether err is the unique
+// constraint on public_id turning down a duplicate
+func isPublicIDCollision(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "forums_public_id_key"
+}
+
+// ResolvePublicID() returns the serial id of the forum with the given
+// public id. A miss on the replica is checked against the primary since
+// the forum may have been created moments ago
+func (m ForumModel) ResolvePublicID(ctx context.Context, publicID string) (int64, error) {
+	if !PublicIDRX.MatchString(publicID) {
+		return 0, ErrRecordNotFound
+	}
+	id, err := m.resolvePublicID(ctx, m.dbFor(true), publicID)
+	if errors.Is(err, ErrRecordNotFound) && m.ReadDB != nil {
+		id, err = m.resolvePublicID(ctx, m.DB, publicID)
+	}
+	return id, err
+}
+
+func (m ForumModel) resolvePublicID(ctx context.Context, db *sql.DB, publicID string) (int64, error) {
+	ctx, span := startQuery(ctx, "ForumModel.ResolvePublicID")
+	query := `
+		SELECT id
+		FROM forums
+		WHERE public_id = $1
+	`
+	// Create a context
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+	var id int64
+	span.statement(query, publicID)
+	err := db.QueryRowContext(ctx, query, publicID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrRecordNotFound
+	}
+	endQuery(span, rowsFor(err), err)
+	return id, err
+}
@@ -0,0 +1,48 @@
+// Filename: internal/data/query.go
+
+package data
+
+import (
+	"strconv"
+	"strings"
+)
+
+// A queryBuilder collects the conditions of a WHERE clause together with
+// their arguments. Placeholders are numbered as values are added, so a
+// filter that is left out can't shift the numbers of the ones after it
+type queryBuilder struct {
+	conditions []string
+	args       []interface{}
+}
+
+// The arg() method adds a value and returns its placeholder, for values
+// used outside the WHERE clause such as the LIMIT
+func (b *queryBuilder) arg(value interface{}) string {
+	b.args = append(b.args, value)
+	return "$" + strconv.Itoa(len(b.args))
+}
+
+// The where() method adds a condition. Each ? in it is replaced by the
+// placeholder of the matching value, in order
+func (b *queryBuilder) where(condition string, values ...interface{}) {
+	parts := strings.Split(condition, "?")
+	if len(parts) != len(values)+1 {
+		panic("query builder: condition " + strconv.Quote(condition) + " does not match its values")
+	}
+	var sb strings.Builder
+	sb.WriteString(parts[0])
+	for i, value := range values {
+		sb.WriteString(b.arg(value))
+		sb.WriteString(parts[i+1])
+	}
+	b.conditions = append(b.conditions, sb.String())
+}
+
+// The whereClause() method joins the conditions, or returns nothing if
+// there are none
+func (b *queryBuilder) whereClause() string {
+	if len(b.conditions) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(b.conditions, "\n\t\tAND ")
+}
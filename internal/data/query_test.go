@@ -0,0 +1,56 @@
+// Filename: internal/data/query_test.go
+
+package data
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQueryBuilder(t *testing.T) {
+	var b queryBuilder
+	if got := b.whereClause(); got != "" {
+		t.Errorf("got %q with no conditions; want an empty clause", got)
+	}
+
+	b.where("archived_at IS NULL")
+	b.where("name = ?", "a")
+	b.where("created_at > ? AND created_at < ?", 1, 2)
+	limit := b.arg(20)
+
+	want := "WHERE archived_at IS NULL\n\t\tAND name = $1\n\t\tAND created_at > $2 AND created_at < $3"
+	if got := b.whereClause(); got != want {
+		t.Errorf("got clause %q; want %q", got, want)
+	}
+	if limit != "$4" {
+		t.Errorf("got placeholder %q for the limit; want $4", limit)
+	}
+	if want := []interface{}{"a", 1, 2, 20}; !reflect.DeepEqual(b.args, want) {
+		t.Errorf("got args %v; want %v", b.args, want)
+	}
+}
+
+// A condition whose ? marks don't match its values is a programming
+// error, caught the first time the query is built
+func TestQueryBuilderMismatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition string
+		values    []interface{}
+	}{
+		{"too few values", "name = ? OR level = ?", []interface{}{"a"}},
+		{"too many values", "name = ?", []interface{}{"a", "b"}},
+		{"values without marks", "archived_at IS NULL", []interface{}{"a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("where() did not panic")
+				}
+			}()
+			var b queryBuilder
+			b.where(tt.condition, tt.values...)
+		})
+	}
+}
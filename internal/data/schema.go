@@ -0,0 +1,131 @@
+// Filename: internal/data/schema.go
+
+package data
+
+import (
+	"AWD_FinalProject.ryanarmstrong.net/internal/validator"
+)
+
+// A FieldRule describes how a single input field is validated.
+// ValidateForum() reads its limits from these rules and the same values
+// are served to clients, so the two can never drift apart
+type FieldRule struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Items       string   `json:"items,omitempty"`
+	Required    bool     `json:"required"`
+	MaxLength   int      `json:"max_length,omitempty"`
+	Pattern     string   `json:"pattern,omitempty"`
+	Minimum     *int     `json:"minimum,omitempty"`
+	Maximum     *int     `json:"maximum,omitempty"`
+	Format      string   `json:"format,omitempty"`
+	MinItems    int      `json:"min_items,omitempty"`
+	MaxItems    int      `json:"max_items,omitempty"`
+	UniqueItems bool     `json:"unique_items,omitempty"`
+	Enum        []string `json:"enum,omitempty"`
+	// ReadOnly fields are sent to clients but can't be set by them
+	ReadOnly bool `json:"read_only,omitempty"`
+}
+
+// The rules for each field of the create/update forum input
+var (
+	ForumNameRule = FieldRule{Name: "name", Type: "string", Required: true, MaxLength: 200}
+
+	ForumLevelRule = FieldRule{Name: "level", Type: "string", Required: true, MaxLength: 200}
+
+	ForumContactRule = FieldRule{Name: "contact", Type: "string", Required: true, MaxLength: 200}
+
+	ForumPhoneRule = FieldRule{Name: "phones.primary", Type: "string", Required: true, Pattern: validator.PhoneRX.String()}
+
+	ForumPhoneExtRule = FieldRule{Name: "phones.extension", Type: "string", MaxLength: 10, Pattern: phoneExtRX.String()}
+
+	ForumPhoneAltRule = FieldRule{Name: "phones.alternate", Type: "string", Pattern: validator.PhoneRX.String()}
+
+	ForumEmailRule = FieldRule{Name: "email", Type: "string", Required: true, Format: "email", Pattern: validator.EmailRX.String()}
+
+	ForumWebsiteRule = FieldRule{Name: "website", Type: "string", Required: true, Format: "uri"}
+
+	ForumAddressRule = FieldRule{Name: "address", Type: "string", Required: true, MaxLength: 500}
+
+	ForumModeRule = FieldRule{Name: "mode", Type: "array", Items: "string", Required: true, MinItems: 1, MaxItems: 5, UniqueItems: true}
+
+	ForumLanguagesRule = FieldRule{Name: "languages", Type: "array", Items: "string", MinItems: 1, MaxItems: 5, UniqueItems: true, Enum: LanguageList}
+
+	ForumPublicContactRule = FieldRule{Name: "public_contact", Type: "boolean"}
+
+	ForumStudentCapacityRule = FieldRule{Name: "student_capacity", Type: "integer", Minimum: intPtr(1), Maximum: intPtr(100000)}
+
+	ForumCurrentEnrollmentRule = FieldRule{Name: "current_enrollment", Type: "integer", Minimum: intPtr(0)}
+
+	ForumEnrollmentPrivateRule = FieldRule{Name: "enrollment_private", Type: "boolean"}
+
+	// The fields of a forum that only the server sets. Archiving has its
+	// own endpoints
+	ForumIDRule            = FieldRule{Name: "id", Type: "integer", ReadOnly: true}
+	ForumPublicIDRule      = FieldRule{Name: "public_id", Type: "string", Pattern: PublicIDRX.String(), ReadOnly: true}
+	ForumCreatedAtRule     = FieldRule{Name: "created_at", Type: "string", Format: "date-time", ReadOnly: true}
+	ForumExpiresAtRule     = FieldRule{Name: "expires_at", Type: "string", Format: "date-time", ReadOnly: true}
+	ForumArchivedAtRule    = FieldRule{Name: "archived_at", Type: "string", Format: "date-time", ReadOnly: true}
+	ForumArchiveReasonRule = FieldRule{Name: "archive_reason", Type: "string", ReadOnly: true}
+	ForumVersionRule       = FieldRule{Name: "version", Type: "integer", ReadOnly: true}
+
+	// The body of POST /v1/forums/:id/archive
+	ArchiveReasonRule = FieldRule{Name: "reason", Type: "string", Required: true, MaxLength: 500}
+
+	// The fields of the create/update FAQ input
+	FAQQuestionRule = FieldRule{Name: "question", Type: "string", Required: true, MaxLength: 300}
+
+	FAQAnswerRule = FieldRule{Name: "answer", Type: "string", Required: true, MaxLength: 2000}
+)
+
+// The intPtr() function lets a rule hold a zero limit that is still
+// written out to clients
+func intPtr(n int) *int {
+	return &n
+}
+
+// ForumSchema() returns the rules for the create/update forum input in
+// the order the fields appear in the request body, followed by the
+// read-only fields
+func ForumSchema() []FieldRule {
+	return []FieldRule{
+		ForumNameRule,
+		ForumLevelRule,
+		ForumContactRule,
+		ForumPhoneRule,
+		ForumPhoneExtRule,
+		ForumPhoneAltRule,
+		ForumEmailRule,
+		ForumWebsiteRule,
+		ForumAddressRule,
+		ForumModeRule,
+		ForumLanguagesRule,
+		ForumPublicContactRule,
+		ForumStudentCapacityRule,
+		ForumCurrentEnrollmentRule,
+		ForumEnrollmentPrivateRule,
+		ForumIDRule,
+		ForumPublicIDRule,
+		ForumCreatedAtRule,
+		ForumExpiresAtRule,
+		ForumArchivedAtRule,
+		ForumArchiveReasonRule,
+		ForumVersionRule,
+	}
+}
+
+// ForumReadOnlyFields() returns the names of the read-only fields in
+// ForumSchema()
+func ForumReadOnlyFields() []string {
+	fields := []string{}
+	for _, rule := range ForumSchema() {
+		if rule.ReadOnly {
+			fields = append(fields, rule.Name)
+		}
+	}
+	return fields
+}
+
+// FAQReadOnlyFields are the fields of a FAQ that only the server sets.
+// Positions change through the order endpoint
+var FAQReadOnlyFields = []string{"id", "position", "version"}
@@ -0,0 +1,243 @@
+// Filename: internal/data/schema_test.go
+
+package data
+
+import (
+	"encoding/json"
+	"math"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+
+	"AWD_FinalProject.ryanarmstrong.net/internal/validator"
+)
+
+// A document that passes every rule
+const validForumDocument = `{
+	"name": "Belize City Study Group",
+	"level": "secondary",
+	"contact": "Ana Chan",
+	"phones": {"primary": "501-223-4455", "extension": "12", "alternate": "501-223-4456"},
+	"email": "ana@example.com",
+	"website": "https://example.com/study",
+	"address": "12 Albert Street",
+	"mode": ["online", "evening"],
+	"languages": ["en", "es"],
+	"public_contact": true,
+	"student_capacity": 40,
+	"current_enrollment": 12,
+	"enrollment_private": false
+}`
+
+// The schema served at GET /v1/forums/schema and ValidateForum() should
+// agree on every document: both accept it, or both refuse it on the same
+// fields. The cross-field rules (enrollment against capacity, the archive
+// reason) aren't in the schema, so the documents leave those alone
+func TestForumSchemaMatchesValidateForum(t *testing.T) {
+	tests := []struct {
+		name string
+		// change is applied to a copy of the valid document
+		change func(doc map[string]interface{})
+		want   []string
+	}{
+		{"valid", func(doc map[string]interface{}) {}, nil},
+		{"no optional fields", func(doc map[string]interface{}) {
+			for _, field := range []string{"languages", "public_contact", "student_capacity", "current_enrollment", "enrollment_private"} {
+				delete(doc, field)
+			}
+			delete(phones(doc), "extension")
+			delete(phones(doc), "alternate")
+		}, nil},
+		{"missing name", func(doc map[string]interface{}) { delete(doc, "name") }, []string{"name"}},
+		{"empty name", func(doc map[string]interface{}) { doc["name"] = "" }, []string{"name"}},
+		{"long name", func(doc map[string]interface{}) { doc["name"] = strings.Repeat("n", 201) }, []string{"name"}},
+		{"longest name", func(doc map[string]interface{}) { doc["name"] = strings.Repeat("n", 200) }, nil},
+		{"long level", func(doc map[string]interface{}) { doc["level"] = strings.Repeat("l", 201) }, []string{"level"}},
+		{"missing contact", func(doc map[string]interface{}) { delete(doc, "contact") }, []string{"contact"}},
+		{"missing phones", func(doc map[string]interface{}) { delete(doc, "phones") }, []string{"phones.primary"}},
+		{"bad primary phone", func(doc map[string]interface{}) { phones(doc)["primary"] = "call me" }, []string{"phones.primary"}},
+		{"letters in extension", func(doc map[string]interface{}) { phones(doc)["extension"] = "12a" }, []string{"phones.extension"}},
+		{"long extension", func(doc map[string]interface{}) { phones(doc)["extension"] = "12345678901" }, []string{"phones.extension"}},
+		{"bad alternate phone", func(doc map[string]interface{}) { phones(doc)["alternate"] = "12" }, []string{"phones.alternate"}},
+		{"missing email", func(doc map[string]interface{}) { delete(doc, "email") }, []string{"email"}},
+		{"bad email", func(doc map[string]interface{}) { doc["email"] = "ana.example.com" }, []string{"email"}},
+		{"missing website", func(doc map[string]interface{}) { delete(doc, "website") }, []string{"website"}},
+		{"bad website", func(doc map[string]interface{}) { doc["website"] = "example dot com" }, []string{"website"}},
+		{"long address", func(doc map[string]interface{}) { doc["address"] = strings.Repeat("a", 501) }, []string{"address"}},
+		{"missing mode", func(doc map[string]interface{}) { delete(doc, "mode") }, []string{"mode"}},
+		{"empty mode", func(doc map[string]interface{}) { doc["mode"] = []interface{}{} }, []string{"mode"}},
+		{"too many modes", func(doc map[string]interface{}) {
+			doc["mode"] = []interface{}{"a", "b", "c", "d", "e", "f"}
+		}, []string{"mode"}},
+		{"repeated mode", func(doc map[string]interface{}) { doc["mode"] = []interface{}{"online", "online"} }, []string{"mode"}},
+		{"empty languages", func(doc map[string]interface{}) { doc["languages"] = []interface{}{} }, []string{"languages"}},
+		{"unknown language", func(doc map[string]interface{}) { doc["languages"] = []interface{}{"en", "fr"} }, []string{"languages"}},
+		{"repeated language", func(doc map[string]interface{}) { doc["languages"] = []interface{}{"en", "en"} }, []string{"languages"}},
+		{"no capacity", func(doc map[string]interface{}) {
+			doc["student_capacity"] = 0.0
+			delete(doc, "current_enrollment")
+		}, []string{"student_capacity"}},
+		{"huge capacity", func(doc map[string]interface{}) { doc["student_capacity"] = 100001.0 }, []string{"student_capacity"}},
+		{"largest capacity", func(doc map[string]interface{}) { doc["student_capacity"] = 100000.0 }, nil},
+		{"negative enrollment", func(doc map[string]interface{}) { doc["current_enrollment"] = -1.0 }, []string{"current_enrollment"}},
+		{"empty enrollment", func(doc map[string]interface{}) { doc["current_enrollment"] = 0.0 }, nil},
+		{"several fields", func(doc map[string]interface{}) {
+			delete(doc, "name")
+			doc["email"] = "nobody"
+			doc["mode"] = []interface{}{}
+		}, []string{"email", "mode", "name"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc map[string]interface{}
+			if err := json.Unmarshal([]byte(validForumDocument), &doc); err != nil {
+				t.Fatal(err)
+			}
+			tt.change(doc)
+			js, err := json.Marshal(doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := checkSchema(ForumSchema(), doc); !slices.Equal(got, tt.want) {
+				t.Errorf("the schema refused %v; want %v", got, tt.want)
+			}
+			if got := validateDocument(t, js); !slices.Equal(got, tt.want) {
+				t.Errorf("ValidateForum() refused %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// The phones() function returns the phones object of a document
+func phones(doc map[string]interface{}) map[string]interface{} {
+	return doc["phones"].(map[string]interface{})
+}
+
+// The validateDocument() function decodes a document the way POST
+// /v1/forums does, then returns the fields ValidateForum() refuses
+func validateDocument(t *testing.T, js []byte) []string {
+	t.Helper()
+	forum := Forum{Languages: DefaultLanguages, PublicContact: true}
+	if err := json.Unmarshal(js, &forum); err != nil {
+		t.Fatal(err)
+	}
+	forum.Phones.Normalize()
+	v := validator.New()
+	ValidateForum(v, &forum)
+	fields := []string{}
+	for field := range v.Errors {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return nilIfEmpty(fields)
+}
+
+// The checkSchema() function applies the published rules to a decoded
+// JSON document without going near ValidateForum(), and returns the
+// fields that break one. As ValidateForum() does, a required string
+// must not be empty and lengths are counted in bytes
+func checkSchema(rules []FieldRule, doc map[string]interface{}) []string {
+	fields := []string{}
+	for _, rule := range rules {
+		if rule.ReadOnly {
+			continue
+		}
+		value, present := lookup(doc, rule.Name)
+		if !present || value == nil || value == "" {
+			if rule.Required {
+				fields = append(fields, rule.Name)
+			}
+			continue
+		}
+		if !followsRule(rule, value) {
+			fields = append(fields, rule.Name)
+		}
+	}
+	sort.Strings(fields)
+	return nilIfEmpty(fields)
+}
+
+// The lookup() function finds a value by its dotted rule name, so
+// "phones.primary" is the primary key of the phones object
+func lookup(doc map[string]interface{}, name string) (interface{}, bool) {
+	var value interface{} = doc
+	for _, key := range strings.Split(name, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = object[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// The followsRule() function checks a present value against one rule
+func followsRule(rule FieldRule, value interface{}) bool {
+	switch rule.Type {
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return false
+		}
+		return followsStringRule(rule, s)
+	case "integer":
+		n, ok := value.(float64)
+		if !ok || n != math.Trunc(n) {
+			return false
+		}
+		if rule.Minimum != nil && n < float64(*rule.Minimum) {
+			return false
+		}
+		return rule.Maximum == nil || n <= float64(*rule.Maximum)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok || len(items) < rule.MinItems || (rule.MaxItems > 0 && len(items) > rule.MaxItems) {
+			return false
+		}
+		seen := make(map[interface{}]bool)
+		for _, item := range items {
+			s, ok := item.(string)
+			if !ok || (rule.UniqueItems && seen[s]) {
+				return false
+			}
+			if rule.Enum != nil && !slices.Contains(rule.Enum, s) {
+				return false
+			}
+			seen[s] = true
+		}
+		return true
+	}
+	return false
+}
+
+// The followsStringRule() function checks a string against a rule's
+// length, pattern and format
+func followsStringRule(rule FieldRule, s string) bool {
+	if rule.MaxLength > 0 && len(s) > rule.MaxLength {
+		return false
+	}
+	if rule.Pattern != "" && !regexp.MustCompile(rule.Pattern).MatchString(s) {
+		return false
+	}
+	if rule.Format == "uri" {
+		u, err := url.Parse(s)
+		return err == nil && u.Scheme != ""
+	}
+	return true
+}
+
+func nilIfEmpty(fields []string) []string {
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
+}
@@ -0,0 +1,77 @@
+// Filename: internal/data/search.go
+
+package data
+
+import (
+	"context"
+)
+
+// A SearchResult is one hit from the site search. Type says what kind of
+// record it is so that other record types can be ranked alongside forums
+type SearchResult struct {
+	Type string `json:"type"`
+	// ID is the public id, the serial id is never shown here
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Snippet string `json:"snippet"`
+	Rank    Float  `json:"rank"`
+	Link    string `json:"link"`
+}
+
+// Search() runs a full-text search over the name and level of open, unexpired
+// forums, best matches first. Each side uses its own GIN index. The
+// snippet is HTML-escaped before ts_headline() adds the highlight tags
+func (m ForumModel) Search(ctx context.Context, q string, page int, pageSize int) ([]*SearchResult, Metadata, error) {
+	ctx, span := startQuery(ctx, "ForumModel.Search")
+	query := `
+		SELECT COUNT(*) OVER(), public_id, name,
+		       ts_headline('simple_unaccent',
+		           replace(replace(replace(replace(name || ' - ' || level, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'),
+		           plainto_tsquery('simple_unaccent', $1), $4),
+		       ts_rank(to_tsvector('simple_unaccent', name) || to_tsvector('simple_unaccent', level), plainto_tsquery('simple_unaccent', $1)) AS rank
+		FROM forums
+		WHERE expires_at > $5
+		AND archived_at IS NULL
+		AND (to_tsvector('simple_unaccent', name) @@ plainto_tsquery('simple_unaccent', $1)
+		     OR to_tsvector('simple_unaccent', level) @@ plainto_tsquery('simple_unaccent', $1))
+		ORDER BY rank DESC, id ASC
+		LIMIT $2 OFFSET $3
+	`
+	// Searches get the same time limit as listings
+	parent := ctx
+	ctx, tx, cancel, err := m.listTx(ctx)
+	if err != nil {
+		endQuery(span, 0, err)
+		return nil, Metadata{}, err
+	}
+	defer cancel()
+	defer tx.Rollback()
+	filters := Filters{Page: page, PageSize: pageSize}
+	args := []interface{}{q, filters.limit(), filters.offset(), m.Highlight.options(), m.now()}
+	span.statement(query, args...)
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		err = queryTimeout(parent, err)
+		endQuery(span, 0, err)
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+	totalRecords := 0
+	results := []*SearchResult{}
+	for rows.Next() {
+		result := SearchResult{Type: "forum"}
+		err := rows.Scan(&totalRecords, &result.ID, &result.Name, &result.Snippet, &result.Rank)
+		if err != nil {
+			endQuery(span, len(results), err)
+			return nil, Metadata{}, err
+		}
+		results = append(results, &result)
+	}
+	if err = rows.Err(); err != nil {
+		err = queryTimeout(parent, err)
+		endQuery(span, len(results), err)
+		return nil, Metadata{}, err
+	}
+	endQuery(span, len(results), nil)
+	return results, calculateMetadata(totalRecords, page, pageSize), nil
+}
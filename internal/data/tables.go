@@ -0,0 +1,54 @@
+// Filename: internal/data/tables.go
+
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Define a TableModel which wraps a sql.DB connection pool. It reads
+// about our tables rather than from them
+type TableModel struct {
+	DB *sql.DB
+}
+
+// Sizes() returns the on-disk size in bytes of each named table, indexes
+// and TOAST included, from a single catalog query. Tables that don't
+// exist are left out
+func (m TableModel) Sizes(ctx context.Context, tables []string) (map[string]int64, error) {
+	ctx, span := startQuery(ctx, "TableModel.Sizes")
+	query := `
+		SELECT name, pg_total_relation_size(to_regclass(name))
+		FROM unnest($1::text[]) AS t(name)
+		WHERE to_regclass(name) IS NOT NULL
+	`
+	// Create a context
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+	span.statement(query, tables)
+	rows, err := m.DB.QueryContext(ctx, query, tables)
+	if err != nil {
+		endQuery(span, 0, err)
+		return nil, err
+	}
+	defer rows.Close()
+	sizes := make(map[string]int64, len(tables))
+	for rows.Next() {
+		var name string
+		var size int64
+		if err := rows.Scan(&name, &size); err != nil {
+			endQuery(span, len(sizes), err)
+			return nil, err
+		}
+		sizes[name] = size
+	}
+	err = rows.Err()
+	endQuery(span, len(sizes), err)
+	if err != nil {
+		return nil, err
+	}
+	return sizes, nil
+}
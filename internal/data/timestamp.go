@@ -0,0 +1,72 @@
+// Filename: internal/data/timestamp.go
+
+package data
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"time"
+)
+
+// A Timestamp is a time that is always written out as RFC 3339 in UTC
+// ("2024-01-15T10:30:00Z"), whatever zone it was read in
+type Timestamp struct {
+	time.Time
+}
+
+// MarshalJSON() writes the time in UTC to the second
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + t.UTC().Format(time.RFC3339) + `"`), nil
+}
+
+// Scan() reads a timestamptz column
+func (t *Timestamp) Scan(src interface{}) error {
+	value, ok := src.(time.Time)
+	if !ok {
+		return fmt.Errorf("cannot scan %T into Timestamp", src)
+	}
+	t.Time = value.UTC()
+	return nil
+}
+
+// Value() writes the time back to the database
+func (t Timestamp) Value() (driver.Value, error) {
+	return t.Time, nil
+}
+
+// A NullTime is a Timestamp for a nullable column. The zero time stands
+// for NULL both ways, so an unset time is written out as null rather
+// than "0001-01-01T00:00:00Z"
+type NullTime struct {
+	time.Time
+}
+
+// MarshalJSON() writes null for the zero time and RFC 3339 otherwise
+func (t NullTime) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+	return Timestamp{Time: t.Time}.MarshalJSON()
+}
+
+// Scan() reads a nullable timestamptz column
+func (t *NullTime) Scan(src interface{}) error {
+	if src == nil {
+		t.Time = time.Time{}
+		return nil
+	}
+	var ts Timestamp
+	if err := ts.Scan(src); err != nil {
+		return err
+	}
+	t.Time = ts.Time
+	return nil
+}
+
+// Value() writes NULL for the zero time
+func (t NullTime) Value() (driver.Value, error) {
+	if t.IsZero() {
+		return nil, nil
+	}
+	return t.Time, nil
+}
@@ -0,0 +1,84 @@
+// Filename: internal/data/timestamp_test.go
+
+package data
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+var belize = time.FixedZone("CST", -6*60*60)
+
+func TestTimestampMarshalJSON(t *testing.T) {
+	ts := Timestamp{Time: time.Date(2026, 1, 15, 4, 30, 0, 999, belize)}
+	js, err := json.Marshal(ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `"2026-01-15T10:30:00Z"`; string(js) != want {
+		t.Errorf("got %s; want %s", js, want)
+	}
+}
+
+func TestNullTimeMarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		time NullTime
+		want string
+	}{
+		{"zero", NullTime{}, "null"},
+		{"set", NullTime{Time: time.Date(2026, 1, 15, 4, 30, 0, 0, belize)}, `"2026-01-15T10:30:00Z"`},
+	}
+	for _, tt := range tests {
+		js, err := json.Marshal(tt.time)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(js) != tt.want {
+			t.Errorf("%s: got %s; want %s", tt.name, js, tt.want)
+		}
+	}
+}
+
+// The zero time stands for NULL going to the database and coming back
+func TestNullTimeScanValue(t *testing.T) {
+	var nt NullTime
+	if v, err := nt.Value(); err != nil || v != nil {
+		t.Errorf("got %v, %v for the zero time; want nil", v, err)
+	}
+
+	set := time.Date(2026, 1, 15, 4, 30, 0, 0, belize)
+	if err := nt.Scan(set); err != nil {
+		t.Fatal(err)
+	}
+	if !nt.Equal(set) || nt.Location() != time.UTC {
+		t.Errorf("got %v; want %v in UTC", nt.Time, set)
+	}
+	if v, err := nt.Value(); err != nil || !v.(time.Time).Equal(set) {
+		t.Errorf("got %v, %v; want %v", v, err, set)
+	}
+
+	if err := nt.Scan(nil); err != nil {
+		t.Fatal(err)
+	}
+	if !nt.IsZero() {
+		t.Errorf("got %v after scanning NULL; want the zero time", nt.Time)
+	}
+	if err := nt.Scan("2026-01-15"); err == nil {
+		t.Error("scanning a string did not fail")
+	}
+}
+
+func TestTimestampScan(t *testing.T) {
+	var ts Timestamp
+	if err := ts.Scan(time.Date(2026, 1, 15, 4, 30, 0, 0, belize)); err != nil {
+		t.Fatal(err)
+	}
+	if ts.Location() != time.UTC {
+		t.Errorf("got zone %v; want UTC", ts.Location())
+	}
+	if err := ts.Scan(nil); err == nil {
+		t.Error("scanning NULL into a Timestamp did not fail")
+	}
+}
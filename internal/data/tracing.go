@@ -0,0 +1,78 @@
+// Filename: internal/data/tracing.go
+
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// The tracer creates a child span around each of our queries. Until a
+// tracer provider is installed this is the global no-op tracer
+var tracer = otel.Tracer("AWD_FinalProject.ryanarmstrong.net/internal/data")
+
+// A querySpan is the span of a query along with what we need to time it
+// and, if it's slow or fails, to log it
+type querySpan struct {
+	trace.Span
+	name  string
+	start time.Time
+	query string
+	args  []interface{}
+}
+
+// The startQuery() function starts the span for the named query
+func startQuery(ctx context.Context, name string) (context.Context, *querySpan) {
+	ctx, span := tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation.name", name),
+		),
+	)
+	return ctx, &querySpan{Span: span, name: name, start: time.Now()}
+}
+
+// The statement() method notes the statement a model method is about to
+// run so that the query log can fingerprint it
+func (s *querySpan) statement(query string, args ...interface{}) {
+	s.query = query
+	s.args = args
+}
+
+// The endQuery() function records the row count and outcome of a query on
+// its span and ends it, and adds its duration to the query metrics.
+// Missing records, edit conflicts and refused FAQ changes are expected
+// outcomes rather than failures
+func endQuery(span *querySpan, rows int, err error) {
+	recordQuery(span, time.Since(span.start), rows, err)
+	span.SetAttributes(attribute.Int("db.response.returned_rows", rows))
+	if err != nil && !expectedError(err) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// The rowsFor() function gives the row count for a single-row statement
+func rowsFor(err error) int {
+	if err != nil {
+		return 0
+	}
+	return 1
+}
+
+// The expectedError() function reports whether err is an outcome the
+// caller handles rather than a failure of the query
+func expectedError(err error) bool {
+	return errors.Is(err, ErrRecordNotFound) ||
+		errors.Is(err, ErrEditConflict) ||
+		errors.Is(err, ErrTooManyFAQs) ||
+		errors.Is(err, ErrFAQOrder)
+}
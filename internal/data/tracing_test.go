@@ -0,0 +1,65 @@
+// Filename: internal/data/tracing_test.go
+
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// A query span is a child of the request's span, carries its row count,
+// and is only marked failed for errors the caller doesn't expect
+func TestQuerySpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(provider) })
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "GET /v1/forums/:id")
+	tests := []struct {
+		name   string
+		rows   int
+		err    error
+		failed bool
+	}{
+		{"TracingTest.Found", 1, nil, false},
+		{"TracingTest.Missing", 0, ErrRecordNotFound, false},
+		{"TracingTest.Conflict", 0, fmt.Errorf("update: %w", ErrEditConflict), false},
+		{"TracingTest.Broken", 0, errors.New("connection reset"), true},
+	}
+	for _, tt := range tests {
+		_, span := startQuery(ctx, tt.name)
+		endQuery(span, tt.rows, tt.err)
+	}
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != len(tests)+1 {
+		t.Fatalf("got %d spans; want %d", len(spans), len(tests)+1)
+	}
+	for i, tt := range tests {
+		span := spans[i]
+		if span.Name() != tt.name {
+			t.Errorf("got span %q; want %q", span.Name(), tt.name)
+			continue
+		}
+		if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("%s is not a child of the request span", tt.name)
+		}
+		if failed := span.Status().Code == codes.Error; failed != tt.failed {
+			t.Errorf("%s marked failed %t; want %t", tt.name, failed, tt.failed)
+		}
+		for _, kv := range span.Attributes() {
+			if kv.Key == "db.response.returned_rows" && kv.Value.AsInt64() != int64(tt.rows) {
+				t.Errorf("%s has %d rows; want %d", tt.name, kv.Value.AsInt64(), tt.rows)
+			}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+// Filename: internal/data/views.go
+
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// TrendingDays is how many days of views count towards the trending sort
+const TrendingDays = 7
+
+// The trendingSince() function returns the last UTC day before the
+// trending window, so the window holds today and the TrendingDays-1 days
+// before it. The days are cut the same way the view counter buckets them
+func trendingSince(now time.Time) string {
+	return now.UTC().Truncate(24*time.Hour).AddDate(0, 0, -TrendingDays).Format(time.DateOnly)
+}
+
+// Define a ViewModel which wraps a sql.DB connection pool
+type ViewModel struct {
+	DB     *sql.DB
+	ReadDB *sql.DB
+}
+
+// Add() adds the counts, keyed by forum id, to the given UTC day in a
+// single statement
+func (m ViewModel) Add(ctx context.Context, day time.Time, counts map[int64]int64) error {
+	if len(counts) == 0 {
+		return nil
+	}
+	ctx, span := startQuery(ctx, "ViewModel.Add")
+	query := `
+		INSERT INTO forum_views (forum_id, day, count)
+		SELECT forum_id, $2::date, count
+		FROM unnest($1::bigint[], $3::bigint[]) AS v(forum_id, count)
+		ON CONFLICT (forum_id, day) DO UPDATE
+		SET count = forum_views.count + EXCLUDED.count
+	`
+	ids := make([]int64, 0, len(counts))
+	values := make([]int64, 0, len(counts))
+	for id, count := range counts {
+		ids = append(ids, id)
+		values = append(values, count)
+	}
+	// Create a context
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+	span.statement(query, ids, day.Format(time.DateOnly), values)
+	_, err := m.DB.ExecContext(ctx, query, ids, day.Format(time.DateOnly), values)
+	endQuery(span, len(ids), err)
+	return err
+}
@@ -0,0 +1,122 @@
+// Filename: internal/i18n/i18n.go
+
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The language used when the client asks for nothing we support
+const DefaultLanguage = "en"
+
+//go:embed locales/*.json
+var localeFS embed.FS
+
+// catalogs maps a language code to its message key -> message map
+var catalogs = loadCatalogs()
+
+// loadCatalogs() reads every embedded translation file. A broken file is a
+// programming error, so we panic at startup rather than serve bad messages
+func loadCatalogs() map[string]map[string]string {
+	files, err := localeFS.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+	result := make(map[string]map[string]string)
+	for _, file := range files {
+		content, err := localeFS.ReadFile(path.Join("locales", file.Name()))
+		if err != nil {
+			panic(err)
+		}
+		messages := make(map[string]string)
+		if err := json.Unmarshal(content, &messages); err != nil {
+			panic(fmt.Sprintf("i18n: %s: %v", file.Name(), err))
+		}
+		result[strings.TrimSuffix(file.Name(), ".json")] = messages
+	}
+	return result
+}
+
+// A Translator resolves message keys for a single language
+type Translator struct {
+	Language string
+	messages map[string]string
+}
+
+// New() returns a Translator for the language, or for English if we
+// have no translations for it
+func New(language string) Translator {
+	messages, ok := catalogs[language]
+	if !ok {
+		language = DefaultLanguage
+		messages = catalogs[DefaultLanguage]
+	}
+	return Translator{Language: language, messages: messages}
+}
+
+// T() returns the message for key formatted with args. Keys missing from
+// the language fall back to English, and unknown keys are returned as is
+func (t Translator) T(key string, args ...interface{}) string {
+	message, ok := t.messages[key]
+	if !ok {
+		message, ok = catalogs[DefaultLanguage][key]
+		if !ok {
+			message = key
+		}
+	}
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+// Negotiate() picks the best supported language from an Accept-Language
+// header value such as "es-BZ,es;q=0.9,en;q=0.8"
+func Negotiate(acceptLanguage string) Translator {
+	type preference struct {
+		language string
+		quality  float64
+	}
+	var preferences []preference
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+				if err != nil {
+					q = 0
+				}
+				quality = q
+			}
+		}
+		// A quality of zero means "not acceptable"
+		if quality <= 0 {
+			continue
+		}
+		// We only translate by primary language, so es-BZ is served as es
+		language := strings.SplitN(tag, "-", 2)[0]
+		preferences = append(preferences, preference{language: language, quality: quality})
+	}
+	// Keep the client's order for languages of equal quality
+	sort.SliceStable(preferences, func(i, j int) bool {
+		return preferences[i].quality > preferences[j].quality
+	})
+	for _, p := range preferences {
+		if _, ok := catalogs[p.language]; ok {
+			return New(p.language)
+		}
+	}
+	return New(DefaultLanguage)
+}
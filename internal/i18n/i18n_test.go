@@ -0,0 +1,91 @@
+// Filename: internal/i18n/i18n_test.go
+
+package i18n
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", "en"},
+		{"es", "es"},
+		{"ES", "es"},
+		{"es-BZ", "es"},
+		{"es-BZ,es;q=0.9,en;q=0.8", "es"},
+		{"en;q=0.5,es;q=0.9", "es"},
+		// Equal qualities keep the client's order
+		{"en,es", "en"},
+		{"es,en", "es"},
+		// Languages we don't have are passed over
+		{"fr,es;q=0.5", "es"},
+		{"fr", "en"},
+		// q=0 means not acceptable, and a broken q counts as zero
+		{"es;q=0,en;q=0.1", "en"},
+		{"es;q=abc", "en"},
+		{" , ;q=1", "en"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := Negotiate(tt.header).Language; got != tt.want {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	es := New("es")
+	if got, want := es.T("not_found"), catalogs["es"]["not_found"]; got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+	if got, want := New("en").T("method_not_allowed", "PUT"), "the PUT method is not supported for this resource"; got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+	// An unknown language is served in English and an unknown key comes
+	// back as it is
+	if got := New("fr").Language; got != DefaultLanguage {
+		t.Errorf("got language %q for fr; want %q", got, DefaultLanguage)
+	}
+	if got := es.T("no_such_key"); got != "no_such_key" {
+		t.Errorf("got %q for an unknown key; want the key", got)
+	}
+}
+
+// A key missing from a language falls back to English
+func TestTranslateFallback(t *testing.T) {
+	tr := Translator{Language: "es", messages: map[string]string{}}
+	if got, want := tr.T("not_found"), catalogs[DefaultLanguage]["not_found"]; got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+}
+
+var verbRX = regexp.MustCompile(`%[a-z]`)
+
+// Every language should have every key, with the same placeholders as
+// the English message so the arguments still line up
+func TestCatalogsMatch(t *testing.T) {
+	english := catalogs[DefaultLanguage]
+	for language, messages := range catalogs {
+		for key, message := range english {
+			translated, ok := messages[key]
+			if !ok {
+				t.Errorf("%s is missing %q", language, key)
+				continue
+			}
+			if want, got := verbRX.FindAllString(message, -1), verbRX.FindAllString(translated, -1); !slices.Equal(got, want) {
+				t.Errorf("%s %q has placeholders %v; want %v", language, key, got, want)
+			}
+		}
+		for key := range messages {
+			if _, ok := english[key]; !ok {
+				t.Errorf("%s has %q, which English doesn't", language, key)
+			}
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// Filename: internal/importer/client.go
+
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"AWD_FinalProject.ryanarmstrong.net/internal/data"
+	"AWD_FinalProject.ryanarmstrong.net/internal/validator"
+)
+
+// The most a registry response may be. The full registry is a few
+// hundred kilobytes, anything much bigger is not what we expect
+const maxRegistryBytes = 16 << 20
+
+// A Centre is one licensed centre as the ministry registry publishes it
+type Centre struct {
+	LicenceNo     string   `json:"licence_no"`
+	Name          string   `json:"centre_name"`
+	Level         string   `json:"level"`
+	ContactPerson string   `json:"contact_person"`
+	Telephone     string   `json:"telephone"`
+	Email         string   `json:"email"`
+	Website       string   `json:"website"`
+	Address       string   `json:"physical_address"`
+	DeliveryModes []string `json:"delivery_modes"`
+	Languages     []string `json:"languages"`
+}
+
+// The registry wraps its centres in an object
+type registry struct {
+	Centres []Centre `json:"centres"`
+}
+
+// A Client fetches the registry from URL
+type Client struct {
+	URL  string
+	HTTP *http.Client
+}
+
+// NewClient() returns a Client for the registry at url
+func NewClient(url string) *Client {
+	return &Client{URL: url, HTTP: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// Fetch() downloads and decodes the whole registry
+func (c *Client) Fetch(ctx context.Context) ([]Centre, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	res, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("registry answered %s", res.Status)
+	}
+	var reg registry
+	dec := json.NewDecoder(io.LimitReader(res.Body, maxRegistryBytes))
+	if err := dec.Decode(&reg); err != nil {
+		return nil, fmt.Errorf("decoding registry: %w", err)
+	}
+	return reg.Centres, nil
+}
+
+// Forum() maps the centre onto the fields of a forum the registry owns.
+// Languages we don't list and repeats are dropped and a centre with none left gets
+// the defaults. The registry publishes its contact details, so like a
+// forum created through the API they are public
+func (c Centre) Forum() *data.Forum {
+	forum := &data.Forum{
+		Name:          strings.TrimSpace(c.Name),
+		Level:         strings.TrimSpace(c.Level),
+		Contact:       strings.TrimSpace(c.ContactPerson),
+		Phones:        data.Phones{Primary: c.Telephone},
+		Email:         strings.TrimSpace(c.Email),
+		Website:       strings.TrimSpace(c.Website),
+		Address:       strings.TrimSpace(c.Address),
+		Mode:          data.Modes{},
+		PublicContact: true,
+	}
+	forum.Phones.Normalize()
+	for _, mode := range c.DeliveryModes {
+		forum.Mode = append(forum.Mode, strings.ToLower(strings.TrimSpace(mode)))
+	}
+	for _, language := range c.Languages {
+		language = strings.ToLower(strings.TrimSpace(language))
+		if validator.In(language, data.LanguageList...) && !validator.In(language, forum.Languages...) {
+			forum.Languages = append(forum.Languages, language)
+		}
+	}
+	if len(forum.Languages) == 0 {
+		forum.Languages = append(data.Languages{}, data.DefaultLanguages...)
+	}
+	return forum
+}
@@ -0,0 +1,195 @@
+// Filename: internal/importer/importer.go
+
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"AWD_FinalProject.ryanarmstrong.net/internal/data"
+	"AWD_FinalProject.ryanarmstrong.net/internal/moderation"
+	"AWD_FinalProject.ryanarmstrong.net/internal/validator"
+)
+
+// At most this many problems are kept in a summary, the rest are only
+// counted
+const maxProblems = 50
+
+// A Summary reports what one import run did
+type Summary struct {
+	Created   int      `json:"created"`
+	Updated   int      `json:"updated"`
+	Unchanged int      `json:"unchanged"`
+	Flagged   int64    `json:"flagged"`
+	Errors    int      `json:"errors"`
+	Problems  []string `json:"problems,omitempty"`
+	// Review lists the centres the content filter let through but
+	// flagged, for a person to look at
+	Review []string `json:"review,omitempty"`
+	// Changed holds the ids of the forums created or updated
+	Changed []int64 `json:"-"`
+}
+
+// The problem() method counts a centre that could not be imported
+func (s *Summary) problem(ref string, err error) {
+	s.Errors++
+	if len(s.Problems) < maxProblems {
+		s.Problems = append(s.Problems, fmt.Sprintf("%s: %v", ref, err))
+	}
+}
+
+// The forum queries an import runs. data.ForumModel provides them
+type ForumStore interface {
+	Insert(ctx context.Context, forum *data.Forum) error
+	GetPrimary(ctx context.Context, id int64) (*data.Forum, error)
+	Update(ctx context.Context, forum *data.Forum) error
+}
+
+// The queries that keep track of imported forums. data.ImportModel
+// provides them
+type RefStore interface {
+	Refs(ctx context.Context) (map[string]int64, error)
+	Flag(ctx context.Context, present []string) (int64, error)
+}
+
+// An Importer reconciles our forums with the registry. Imported text
+// goes through the same content filter as the API's write routes
+type Importer struct {
+	Client  *Client
+	Forums  ForumStore
+	Imports RefStore
+	Filter  *moderation.Filter
+}
+
+// Run() fetches the registry and brings our forums in line with it.
+// Centres we haven't seen are created, changed ones are updated and
+// forums the registry no longer lists are flagged for an admin to look
+// at, never deleted. Running it twice against the same registry changes
+// nothing the second time. An error is only returned when the run could
+// not happen at all, problems with single centres go in the summary
+func (imp *Importer) Run(ctx context.Context) (Summary, error) {
+	var summary Summary
+	centres, err := imp.Client.Fetch(ctx)
+	if err != nil {
+		return summary, err
+	}
+	refs, err := imp.Imports.Refs(ctx)
+	if err != nil {
+		return summary, err
+	}
+	present := []string{}
+	seen := make(map[string]bool)
+	for _, centre := range centres {
+		ref := strings.TrimSpace(centre.LicenceNo)
+		if ref == "" {
+			summary.problem("(no licence number)", errors.New("centre has no licence number"))
+			continue
+		}
+		if seen[ref] {
+			summary.problem(ref, errors.New("licence number listed more than once"))
+			continue
+		}
+		seen[ref] = true
+		present = append(present, ref)
+		incoming := centre.Forum()
+		v := validator.New()
+		if data.ValidateForum(v, incoming); !v.Valid() {
+			summary.problem(ref, invalid(v))
+			continue
+		}
+		// Like the API, a borderline centre is still taken
+		result := imp.Filter.Score(incoming)
+		if result.Rejected() {
+			summary.problem(ref, rejected(result))
+			continue
+		}
+		if result.Flagged() && len(summary.Review) < maxProblems {
+			summary.Review = append(summary.Review, ref)
+		}
+		id, ok := refs[ref]
+		if !ok {
+			incoming.ExternalRef = ref
+			if err := imp.Forums.Insert(ctx, incoming); err != nil {
+				summary.problem(ref, err)
+				continue
+			}
+			summary.Created++
+			summary.Changed = append(summary.Changed, incoming.ID)
+			continue
+		}
+		forum, err := imp.Forums.GetPrimary(ctx, id)
+		if err != nil {
+			summary.problem(ref, err)
+			continue
+		}
+		if !merge(forum, incoming) {
+			summary.Unchanged++
+			continue
+		}
+		if err := imp.Forums.Update(ctx, forum); err != nil {
+			summary.problem(ref, err)
+			continue
+		}
+		summary.Updated++
+		summary.Changed = append(summary.Changed, forum.ID)
+	}
+	summary.Flagged, err = imp.Imports.Flag(ctx, present)
+	if err != nil {
+		return summary, err
+	}
+	return summary, nil
+}
+
+// The merge() function copies the fields the registry owns from incoming
+// onto forum and reports whether any of them changed. The rest, such as
+// capacity and whether the contact details are public, stay ours
+func merge(forum, incoming *data.Forum) bool {
+	same := forum.Name == incoming.Name &&
+		forum.Level == incoming.Level &&
+		forum.Contact == incoming.Contact &&
+		forum.Phones.Primary == incoming.Phones.Primary &&
+		forum.Email == incoming.Email &&
+		forum.Website == incoming.Website &&
+		forum.Address == incoming.Address &&
+		slices.Equal(forum.Mode, incoming.Mode) &&
+		slices.Equal(forum.Languages, incoming.Languages)
+	if same {
+		return false
+	}
+	forum.Name = incoming.Name
+	forum.Level = incoming.Level
+	forum.Contact = incoming.Contact
+	forum.Phones.Primary = incoming.Phones.Primary
+	forum.Email = incoming.Email
+	forum.Website = incoming.Website
+	forum.Address = incoming.Address
+	forum.Mode = incoming.Mode
+	forum.Languages = incoming.Languages
+	return true
+}
+
+// The invalid() function lists the failed checks of a centre. The keys
+// are left untranslated since the summary is only read by admins
+func invalid(v *validator.Validator) error {
+	fields := make([]string, 0, len(v.Errors))
+	for field, key := range v.Errors {
+		fields = append(fields, field+" "+key)
+	}
+	slices.Sort(fields)
+	return errors.New(strings.Join(fields, ", "))
+}
+
+// The rejected() function lists the fields the content filter refused
+func rejected(result moderation.Result) error {
+	fields := make([]string, 0, len(result.Fields))
+	for field, score := range result.Fields {
+		if score >= moderation.Reject {
+			fields = append(fields, field)
+		}
+	}
+	slices.Sort(fields)
+	return errors.New("refused by the content filter: " + strings.Join(fields, ", "))
+}
@@ -0,0 +1,272 @@
+// Filename: internal/importer/importer_test.go
+
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"AWD_FinalProject.ryanarmstrong.net/internal/data"
+	"AWD_FinalProject.ryanarmstrong.net/internal/moderation"
+)
+
+// A memoryStore stands in for the forum and import models. It keeps the
+// forums in a map and counts the writes made to them
+type memoryStore struct {
+	forums  map[int64]data.Forum
+	missing map[int64]time.Time
+	nextID  int64
+	inserts int
+	updates int
+	now     time.Time
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		forums:  make(map[int64]data.Forum),
+		missing: make(map[int64]time.Time),
+		now:     time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func (s *memoryStore) Insert(ctx context.Context, forum *data.Forum) error {
+	s.nextID++
+	forum.ID = s.nextID
+	forum.Version = 1
+	s.forums[forum.ID] = *forum
+	s.inserts++
+	return nil
+}
+
+func (s *memoryStore) GetPrimary(ctx context.Context, id int64) (*data.Forum, error) {
+	forum, ok := s.forums[id]
+	if !ok {
+		return nil, data.ErrRecordNotFound
+	}
+	return &forum, nil
+}
+
+func (s *memoryStore) Update(ctx context.Context, forum *data.Forum) error {
+	forum.Version++
+	s.forums[forum.ID] = *forum
+	s.updates++
+	return nil
+}
+
+func (s *memoryStore) Refs(ctx context.Context) (map[string]int64, error) {
+	refs := make(map[string]int64)
+	for id, forum := range s.forums {
+		if forum.ExternalRef != "" {
+			refs[forum.ExternalRef] = id
+		}
+	}
+	return refs, nil
+}
+
+// Flag() keeps the time a forum was first found missing, as the query does
+func (s *memoryStore) Flag(ctx context.Context, present []string) (int64, error) {
+	for id, forum := range s.forums {
+		if forum.ExternalRef == "" {
+			continue
+		}
+		if slices.Contains(present, forum.ExternalRef) {
+			delete(s.missing, id)
+		} else if _, ok := s.missing[id]; !ok {
+			s.missing[id] = s.now
+		}
+	}
+	return int64(len(s.missing)), nil
+}
+
+// A testRegistry serves a list of centres that the test can change
+// between runs
+type testRegistry struct {
+	mu      sync.Mutex
+	centres []Centre
+}
+
+func (reg *testRegistry) set(centres ...Centre) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.centres = centres
+}
+
+func (reg *testRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	json.NewEncoder(w).Encode(registry{Centres: reg.centres})
+}
+
+// The newTestImporter() function returns an importer that reads from a
+// local registry and writes to memory
+func newTestImporter(t *testing.T) (*Importer, *testRegistry, *memoryStore) {
+	t.Helper()
+	reg := &testRegistry{}
+	srv := httptest.NewServer(reg)
+	t.Cleanup(srv.Close)
+	store := newMemoryStore()
+	imp := &Importer{
+		Client:  NewClient(srv.URL),
+		Forums:  store,
+		Imports: store,
+		Filter:  moderation.New(),
+	}
+	return imp, reg, store
+}
+
+// The centre() function returns a registry entry that passes validation
+func centre(licence, name string) Centre {
+	return Centre{
+		LicenceNo:     licence,
+		Name:          name,
+		Level:         "Primary",
+		ContactPerson: "Ana Pech",
+		Telephone:     "501-600-1234",
+		Email:         "centre@example.com",
+		Website:       "https://centre.example.com",
+		Address:       "12 Regent Street, Belize City",
+		DeliveryModes: []string{"In Person"},
+		Languages:     []string{"English"},
+	}
+}
+
+func (imp *Importer) mustRun(t *testing.T) Summary {
+	t.Helper()
+	summary, err := imp.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return summary
+}
+
+// Running again against the same registry writes nothing
+func TestRunIdempotent(t *testing.T) {
+	imp, reg, store := newTestImporter(t)
+	reg.set(centre("L-001", "Sunrise Preschool"), centre("L-002", "Hilltop Academy"))
+
+	first := imp.mustRun(t)
+	if first.Created != 2 || first.Updated != 0 || first.Errors != 0 {
+		t.Fatalf("got %+v on the first run; want 2 created", first)
+	}
+	second := imp.mustRun(t)
+	if second.Created != 0 || second.Updated != 0 || second.Unchanged != 2 || len(second.Changed) != 0 {
+		t.Errorf("got %+v on the second run; want nothing changed", second)
+	}
+	if store.inserts != 2 || store.updates != 0 || len(store.forums) != 2 {
+		t.Errorf("got %d inserts and %d updates; want only the first run's", store.inserts, store.updates)
+	}
+
+	// A change the registry makes is picked up once, and what the
+	// registry doesn't own is left as it was
+	forum := store.forums[1]
+	capacity := int32(20)
+	forum.StudentCapacity = &capacity
+	store.forums[1] = forum
+	renamed := centre("L-001", "Sunrise Early Learning")
+	reg.set(renamed, centre("L-002", "Hilltop Academy"))
+	if summary := imp.mustRun(t); summary.Updated != 1 || !slices.Equal(summary.Changed, []int64{1}) {
+		t.Errorf("got %+v after a rename; want forum 1 updated", summary)
+	}
+	if got := store.forums[1]; got.Name != "Sunrise Early Learning" || got.StudentCapacity == nil {
+		t.Errorf("got %+v; want the new name and our capacity kept", got)
+	}
+	if summary := imp.mustRun(t); summary.Updated != 0 {
+		t.Errorf("got %+v on the run after; want nothing changed", summary)
+	}
+}
+
+// A forum the registry stops listing is flagged and kept. It stays
+// flagged from the first time it went missing, and the flag clears when
+// it comes back
+func TestRunFlagsMissing(t *testing.T) {
+	imp, reg, store := newTestImporter(t)
+	reg.set(centre("L-001", "Sunrise Preschool"), centre("L-002", "Hilltop Academy"))
+	imp.mustRun(t)
+	local := &data.Forum{Name: "Our own forum"}
+	store.Insert(context.Background(), local)
+
+	reg.set(centre("L-002", "Hilltop Academy"))
+	summary := imp.mustRun(t)
+	if summary.Flagged != 1 {
+		t.Errorf("got %d flagged; want 1", summary.Flagged)
+	}
+	since, ok := store.missing[1]
+	if !ok {
+		t.Fatal("the forum the registry dropped was not flagged")
+	}
+	store.now = store.now.Add(24 * time.Hour)
+	imp.mustRun(t)
+	if !store.missing[1].Equal(since) {
+		t.Errorf("got missing since %v; want the first time, %v", store.missing[1], since)
+	}
+
+	// Nothing is ever deleted, even when the registry comes back empty
+	reg.set()
+	if summary := imp.mustRun(t); summary.Flagged != 2 {
+		t.Errorf("got %d flagged for an empty registry; want 2", summary.Flagged)
+	}
+	if len(store.forums) != 3 {
+		t.Errorf("got %d forums; want all 3 kept", len(store.forums))
+	}
+	if _, ok := store.missing[local.ID]; ok {
+		t.Error("a forum that was never imported was flagged")
+	}
+
+	reg.set(centre("L-001", "Sunrise Preschool"), centre("L-002", "Hilltop Academy"))
+	if summary := imp.mustRun(t); summary.Flagged != 0 || summary.Created != 0 {
+		t.Errorf("got %+v once the centres came back; want them unflagged, not recreated", summary)
+	}
+}
+
+// Centres that fail validation or the content filter are reported and
+// not written. Borderline ones are written and listed for review
+func TestRunProblems(t *testing.T) {
+	imp, reg, store := newTestImporter(t)
+	noName := centre("L-003", "")
+	banned := centre("L-004", "Casino Night Classes")
+	borderline := centre("L-005", "Homework Club")
+	borderline.Address = "see www.homework.example.com"
+	reg.set(centre("L-001", "Sunrise Preschool"), centre("L-001", "Sunrise Again"), centre("", "No Licence"), noName, banned, borderline)
+
+	summary := imp.mustRun(t)
+	if summary.Created != 2 || summary.Errors != 4 || len(summary.Problems) != 4 {
+		t.Fatalf("got %+v; want 2 created and 4 problems", summary)
+	}
+	want := []string{
+		"L-001: licence number listed more than once",
+		"(no licence number): centre has no licence number",
+		"L-003: name required",
+		"L-004: refused by the content filter: name",
+	}
+	if !slices.Equal(summary.Problems, want) {
+		t.Errorf("got problems %q; want %q", summary.Problems, want)
+	}
+	if !slices.Equal(summary.Review, []string{"L-005"}) {
+		t.Errorf("got %v for review; want L-005", summary.Review)
+	}
+	if len(store.forums) != 2 {
+		t.Errorf("got %d forums written; want 2", len(store.forums))
+	}
+}
+
+// A registry that can't be read stops the run before anything is written
+func TestRunRegistryDown(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	store := newMemoryStore()
+	imp := &Importer{Client: NewClient(srv.URL), Forums: store, Imports: store, Filter: moderation.New()}
+	if _, err := imp.Run(context.Background()); err == nil {
+		t.Error("got no error from a registry that is down")
+	}
+	if len(store.missing) != 0 {
+		t.Error("forums were flagged after a failed fetch")
+	}
+}
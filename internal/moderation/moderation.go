@@ -0,0 +1,211 @@
+// Filename: internal/moderation/moderation.go
+
+package moderation
+
+import (
+	"bufio"
+	_ "embed"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"AWD_FinalProject.ryanarmstrong.net/internal/data"
+)
+
+//go:embed terms.txt
+var defaultTerms string
+
+// The score thresholds. A forum at or above Reject is refused and one at
+// or above Review is accepted but flagged
+const (
+	Review = 4
+	Reject = 10
+)
+
+// How much each finding adds to a field's score
+const (
+	termScore = Reject
+	urlScore  = 4
+)
+
+// urlRX matches links and bare domain names. Only the website field is
+// meant to hold one, so each link anywhere else counts against the forum
+var urlRX = regexp.MustCompile(`(?i)(?:https?://|www\.)\S+|\b[a-z0-9-]+\.(?:com|net|org|info|biz|io|xyz|ru|top|click)\b`)
+
+// A Filter scores forum text against a list of banned terms
+type Filter struct {
+	terms [][]string
+}
+
+// New() returns a Filter with the embedded default terms and any extra ones
+func New(extra ...string) *Filter {
+	f := &Filter{}
+	f.addTerms(strings.Split(defaultTerms, "\n"))
+	f.addTerms(extra)
+	return f
+}
+
+// LoadFile() adds the terms in the file, one per line, to the filter
+func (f *Filter) LoadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	f.addTerms(lines)
+	return nil
+}
+
+// The addTerms() method normalizes each term the same way as the text it
+// is matched against, skipping blank lines and comments
+func (f *Filter) addTerms(lines []string) {
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if words := words(line); len(words) > 0 {
+			f.terms = append(f.terms, words)
+		}
+	}
+}
+
+// A Result holds the score of each field that had findings and the
+// highest of them
+type Result struct {
+	Score  int
+	Fields map[string]int
+}
+
+// Rejected() reports whether the forum should be refused outright
+func (r Result) Rejected() bool {
+	return r.Score >= Reject
+}
+
+// Flagged() reports whether the forum is suspicious but not bad enough to
+// be refused
+func (r Result) Flagged() bool {
+	return r.Score >= Review && r.Score < Reject
+}
+
+// Score() checks the free-text fields of a forum. The website is left
+// out of the link count since that is where a link belongs
+func (f *Filter) Score(forum *data.Forum) Result {
+	return f.scoreFields(map[string]string{
+		"name":    forum.Name,
+		"level":   forum.Level,
+		"contact": forum.Contact,
+		"address": forum.Address,
+	})
+}
+
+// ScoreFAQ() checks the question and answer of a FAQ the same way
+func (f *Filter) ScoreFAQ(faq *data.FAQ) Result {
+	return f.scoreFields(map[string]string{
+		"question": faq.Question,
+		"answer":   faq.Answer,
+	})
+}
+
+// The scoreFields() method scores each field's text for banned terms and
+// links
+func (f *Filter) scoreFields(fields map[string]string) Result {
+	result := Result{Fields: make(map[string]int)}
+	for field, text := range fields {
+		score := f.termHits(text)*termScore + len(urlRX.FindAllString(clean(text), -1))*urlScore
+		if score == 0 {
+			continue
+		}
+		result.Fields[field] = score
+		if score > result.Score {
+			result.Score = score
+		}
+	}
+	return result
+}
+
+// The termHits() method counts the banned terms found in the text
+func (f *Filter) termHits(text string) int {
+	tokens := words(text)
+	hits := 0
+	for _, term := range f.terms {
+		if containsRun(tokens, term) {
+			hits++
+		}
+	}
+	return hits
+}
+
+// The containsRun() function reports whether term appears as consecutive
+// words in tokens
+func containsRun(tokens, term []string) bool {
+	for i := 0; i+len(term) <= len(tokens); i++ {
+		match := true
+		for j := range term {
+			if tokens[i+j] != term[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// Characters commonly swapped in for letters to get past filters
+var lookalikes = strings.NewReplacer(
+	"0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "@", "a", "$", "s",
+)
+
+// The clean() function folds case, turns fullwidth forms into ASCII and
+// drops invisible characters such as zero-width spaces and soft hyphens
+func clean(text string) string {
+	return strings.Map(func(r rune) rune {
+		// Fullwidth ASCII variants (U+FF01 to U+FF5E)
+		if r >= 0xFF01 && r <= 0xFF5E {
+			r -= 0xFEE0
+		}
+		if unicode.Is(unicode.Cf, r) {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, text)
+}
+
+// The words() function splits normalized text into words. Runs of single
+// letters are joined back together so "s p a m" and "s.p.a.m" read as
+// "spam"
+func words(text string) []string {
+	text = lookalikes.Replace(clean(text))
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	var result []string
+	var run strings.Builder
+	for _, field := range fields {
+		if len([]rune(field)) == 1 {
+			run.WriteString(field)
+			continue
+		}
+		if run.Len() > 0 {
+			result = append(result, run.String())
+			run.Reset()
+		}
+		result = append(result, field)
+	}
+	if run.Len() > 0 {
+		result = append(result, run.String())
+	}
+	return result
+}
@@ -0,0 +1,126 @@
+// Filename: internal/moderation/moderation_test.go
+
+package moderation
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"AWD_FinalProject.ryanarmstrong.net/internal/data"
+)
+
+func TestScore(t *testing.T) {
+	f := New("free money")
+	tests := []struct {
+		name  string
+		text  string
+		score int
+	}{
+		{"clean", "Saturday maths help for CXC students", 0},
+		{"banned term", "Best casino in town", termScore},
+		{"upper case", "CASINO NIGHT", termScore},
+		{"spaced letters", "c a s i n o", termScore},
+		{"dotted letters", "c.a.s.i.n.o", termScore},
+		{"digits for letters", "c4s1n0", termScore},
+		{"zero-width space", "cas\u200bino", termScore},
+		{"soft hyphen", "cas\u00adino", termScore},
+		{"fullwidth letters", "\uff43\uff41\uff53\uff49\uff4e\uff4f", termScore},
+		// Terms match whole words only
+		{"inside a word", "A special occasion", 0},
+		{"plural", "casinos", 0},
+		// A term of several words needs them in a row
+		{"phrase", "Get FREE money now", termScore},
+		{"phrase split up", "free lessons, no money", 0},
+		{"two terms", "casino and free money", 2 * termScore},
+		{"link", "see www.example.com", urlScore},
+		{"bare domain", "cheap-essays.xyz for you", urlScore},
+		{"two links", "https://a.example and b.com", 2 * urlScore},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := f.Score(&data.Forum{Name: tt.text})
+			if result.Score != tt.score {
+				t.Errorf("got score %d; want %d", result.Score, tt.score)
+			}
+			if got := result.Fields["name"]; got != tt.score {
+				t.Errorf("got name score %d; want %d", got, tt.score)
+			}
+		})
+	}
+}
+
+// The forum's score is that of its worst field, and the website may hold
+// a link
+func TestScoreFields(t *testing.T) {
+	forum := &data.Forum{
+		Name:    "Study group",
+		Contact: "Ana, www.example.com",
+		Address: "Casino Road",
+		Website: "https://example.com",
+	}
+	result := New().Score(forum)
+	if result.Score != termScore {
+		t.Errorf("got score %d; want %d", result.Score, termScore)
+	}
+	want := map[string]int{"contact": urlScore, "address": termScore}
+	if len(result.Fields) != len(want) {
+		t.Errorf("got fields %v; want %v", result.Fields, want)
+	}
+	for field, score := range want {
+		if result.Fields[field] != score {
+			t.Errorf("got %s score %d; want %d", field, result.Fields[field], score)
+		}
+	}
+}
+
+func TestResultThresholds(t *testing.T) {
+	tests := []struct {
+		score    int
+		flagged  bool
+		rejected bool
+	}{
+		{0, false, false},
+		{Review - 1, false, false},
+		{Review, true, false},
+		{Reject - 1, true, false},
+		{Reject, false, true},
+		{Reject + 5, false, true},
+	}
+	for _, tt := range tests {
+		r := Result{Score: tt.score}
+		if r.Flagged() != tt.flagged || r.Rejected() != tt.rejected {
+			t.Errorf("score %d: got flagged %t, rejected %t; want %t, %t", tt.score, r.Flagged(), r.Rejected(), tt.flagged, tt.rejected)
+		}
+	}
+}
+
+func TestScoreFAQ(t *testing.T) {
+	result := New().ScoreFAQ(&data.FAQ{Question: "Is it free?", Answer: "Ask at the v-i-a-g-r-a desk"})
+	if !result.Rejected() || result.Fields["answer"] != termScore || result.Fields["question"] != 0 {
+		t.Errorf("got %+v; want the answer refused", result)
+	}
+}
+
+// Extra terms load from a file, skipping blank lines and comments
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "terms.txt")
+	if err := os.WriteFile(path, []byte("# local terms\n\nhomework service\n  essay mill  \n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	f := New()
+	if err := f.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	for _, text := range []string{"Homework Service", "an essay mill", "casino"} {
+		if got := f.Score(&data.Forum{Name: text}); !got.Rejected() {
+			t.Errorf("%q was not refused", text)
+		}
+	}
+	if got := f.Score(&data.Forum{Name: "local terms"}); got.Score != 0 {
+		t.Errorf("a comment line was loaded as a term")
+	}
+	if err := f.LoadFile(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("loading a missing file did not fail")
+	}
+}
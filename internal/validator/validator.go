@@ -14,14 +14,18 @@ var (
 )
 
 // We create a type that wraps our validation errors map
+// The error values are message keys which are translated for the client,
+// and Args holds the values for any placeholders in those messages
 type Validator struct {
 	Errors map[string]string
+	Args   map[string][]interface{}
 }
 
 // New() creates a new Validator instance
 func New() *Validator {
 	return &Validator{
 		Errors: make(map[string]string),
+		Args:   make(map[string][]interface{}),
 	}
 }
 
@@ -40,6 +44,16 @@ func In(element string, list ...string) bool {
 	return false
 }
 
+// EachIn() checks that every element of values is in the list
+func EachIn(values []string, list ...string) bool {
+	for _, value := range values {
+		if !In(value, list...) {
+			return false
+		}
+	}
+	return true
+}
+
 // Matches() returns true if a string value matches a specific regex pattern
 func Matches(value string, rx *regexp.Regexp) bool {
 	return rx.MatchString(value)
@@ -52,17 +66,20 @@ func ValidWebsite(website string) bool {
 }
 
 // AddError() adds an error entry to the Errors map
-func (v *Validator) AddError(key, message string) {
+func (v *Validator) AddError(key, message string, args ...interface{}) {
 	if _, exists := v.Errors[key]; !exists {
 		v.Errors[key] = message
+		if len(args) > 0 {
+			v.Args[key] = args
+		}
 	}
 }
 
 // Check() performs the validation checks and calls the AddError
 // method in turn if an error entry needs to be added
-func (v *Validator) Check(ok bool, key, message string) {
+func (v *Validator) Check(ok bool, key, message string, args ...interface{}) {
 	if !ok {
-		v.AddError(key, message)
+		v.AddError(key, message, args...)
 	}
 }
 
@@ -0,0 +1,4 @@
+-- Filename: migrations/000004_add_forums_expires_at.down.sql
+
+DROP INDEX IF EXISTS forums_expires_at_idx;
+ALTER TABLE forums DROP COLUMN IF EXISTS expires_at;
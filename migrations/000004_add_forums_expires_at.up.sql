@@ -0,0 +1,9 @@
+-- Filename: migrations/000004_add_forums_expires_at.up.sql
+
+ALTER TABLE forums ADD COLUMN IF NOT EXISTS expires_at timestamp(0) with time zone;
+-- Existing forums get a full year from now rather than from creation, so
+-- none of them is hidden the moment this runs
+UPDATE forums SET expires_at = GREATEST(created_at, NOW()) + INTERVAL '1 year' WHERE expires_at IS NULL;
+ALTER TABLE forums ALTER COLUMN expires_at SET DEFAULT NOW() + INTERVAL '1 year';
+ALTER TABLE forums ALTER COLUMN expires_at SET NOT NULL;
+CREATE INDEX IF NOT EXISTS forums_expires_at_idx ON forums (expires_at);
@@ -0,0 +1,3 @@
+-- Filename: migrations/000005_add_forums_public_contact.down.sql
+
+ALTER TABLE forums DROP COLUMN IF EXISTS public_contact;
@@ -0,0 +1,3 @@
+-- Filename: migrations/000005_add_forums_public_contact.up.sql
+
+ALTER TABLE forums ADD COLUMN IF NOT EXISTS public_contact boolean NOT NULL DEFAULT true;
@@ -0,0 +1,3 @@
+-- Filename: migrations/000006_create_forum_changes_table.down.sql
+
+DROP TABLE IF EXISTS forum_changes;
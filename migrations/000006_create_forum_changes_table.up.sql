@@ -0,0 +1,10 @@
+-- Filename: migrations/000006_create_forum_changes_table.up.sql
+
+-- forum_id has no foreign key so that deletes stay in the feed
+CREATE TABLE IF NOT EXISTS forum_changes (
+    id bigserial PRIMARY KEY,
+    forum_id bigint NOT NULL,
+    change_type text NOT NULL CHECK (change_type IN ('created', 'updated', 'deleted')),
+    changed_at timestamp with time zone NOT NULL DEFAULT clock_timestamp()
+);
+CREATE INDEX IF NOT EXISTS forum_changes_changed_at_idx ON forum_changes (changed_at);
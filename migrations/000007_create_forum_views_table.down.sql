@@ -0,0 +1,3 @@
+-- Filename: migrations/000007_create_forum_views_table.down.sql
+
+DROP TABLE IF EXISTS forum_views;
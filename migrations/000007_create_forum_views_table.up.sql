@@ -0,0 +1,10 @@
+-- Filename: migrations/000007_create_forum_views_table.up.sql
+
+-- One row per forum per UTC day. Like forum_changes there is no foreign
+-- key, so a flush never fails because a forum was deleted in the meantime
+CREATE TABLE IF NOT EXISTS forum_views (
+    forum_id bigint NOT NULL,
+    day date NOT NULL,
+    count bigint NOT NULL DEFAULT 0 CHECK (count >= 0),
+    PRIMARY KEY (forum_id, day)
+);
@@ -0,0 +1,3 @@
+-- Filename: migrations/000008_create_forum_faqs_table.down.sql
+
+DROP TABLE IF EXISTS forum_faqs;
@@ -0,0 +1,13 @@
+-- Filename: migrations/000008_create_forum_faqs_table.up.sql
+
+-- FAQs belong to their forum and go with it. Positions run 1..n per forum;
+-- the unique check is deferred so a reorder can shuffle them in one go
+CREATE TABLE IF NOT EXISTS forum_faqs (
+    id bigserial PRIMARY KEY,
+    forum_id bigint NOT NULL REFERENCES forums ON DELETE CASCADE,
+    question text NOT NULL CHECK (octet_length(question) <= 300),
+    answer text NOT NULL CHECK (octet_length(answer) <= 2000),
+    position integer NOT NULL CHECK (position > 0),
+    version integer NOT NULL DEFAULT 1,
+    CONSTRAINT forum_faqs_position_key UNIQUE (forum_id, position) DEFERRABLE INITIALLY DEFERRED
+);
@@ -0,0 +1,9 @@
+-- Filename: migrations/000009_add_forums_capacity.down.sql
+
+ALTER TABLE forums DROP CONSTRAINT IF EXISTS current_enrollment_capacity_check;
+ALTER TABLE forums DROP CONSTRAINT IF EXISTS current_enrollment_check;
+ALTER TABLE forums DROP CONSTRAINT IF EXISTS student_capacity_check;
+
+ALTER TABLE forums DROP COLUMN IF EXISTS enrollment_private;
+ALTER TABLE forums DROP COLUMN IF EXISTS current_enrollment;
+ALTER TABLE forums DROP COLUMN IF EXISTS student_capacity;
@@ -0,0 +1,9 @@
+-- Filename: migrations/000009_add_forums_capacity.up.sql
+
+ALTER TABLE forums ADD COLUMN IF NOT EXISTS student_capacity integer;
+ALTER TABLE forums ADD COLUMN IF NOT EXISTS current_enrollment integer;
+ALTER TABLE forums ADD COLUMN IF NOT EXISTS enrollment_private boolean NOT NULL DEFAULT false;
+
+ALTER TABLE forums ADD CONSTRAINT student_capacity_check CHECK (student_capacity BETWEEN 1 AND 100000);
+ALTER TABLE forums ADD CONSTRAINT current_enrollment_check CHECK (current_enrollment >= 0 AND current_enrollment <= student_capacity);
+ALTER TABLE forums ADD CONSTRAINT current_enrollment_capacity_check CHECK (current_enrollment IS NULL OR student_capacity IS NOT NULL);
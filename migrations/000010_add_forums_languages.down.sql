@@ -0,0 +1,6 @@
+-- Filename: migrations/000010_add_forums_languages.down.sql
+
+DROP INDEX IF EXISTS forums_languages_idx;
+ALTER TABLE forums DROP CONSTRAINT IF EXISTS languages_allowed_check;
+ALTER TABLE forums DROP CONSTRAINT IF EXISTS languages_length_check;
+ALTER TABLE forums DROP COLUMN IF EXISTS languages;
@@ -0,0 +1,6 @@
+-- Filename: migrations/000010_add_forums_languages.up.sql
+
+ALTER TABLE forums ADD COLUMN IF NOT EXISTS languages text[] NOT NULL DEFAULT '{en}';
+ALTER TABLE forums ADD CONSTRAINT languages_length_check CHECK (array_length(languages, 1) BETWEEN 1 AND 5);
+ALTER TABLE forums ADD CONSTRAINT languages_allowed_check CHECK (languages <@ ARRAY['en', 'es', 'bzj', 'mop', 'kek']);
+CREATE INDEX IF NOT EXISTS forums_languages_idx ON forums USING GIN (languages);
@@ -0,0 +1,4 @@
+-- Filename: migrations/000011_add_forums_public_id.down.sql
+
+ALTER TABLE forums DROP CONSTRAINT IF EXISTS forums_public_id_key;
+ALTER TABLE forums DROP COLUMN IF EXISTS public_id;
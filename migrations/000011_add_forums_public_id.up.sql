@@ -0,0 +1,25 @@
+-- Filename: migrations/000011_add_forums_public_id.up.sql
+
+ALTER TABLE forums ADD COLUMN IF NOT EXISTS public_id text;
+
+-- Give every existing forum a random 10 character base32 id, drawing
+-- again on the rare collision
+DO $$
+DECLARE
+    forum record;
+    candidate text;
+BEGIN
+    FOR forum IN SELECT id FROM forums WHERE public_id IS NULL LOOP
+        LOOP
+            SELECT string_agg(substr('abcdefghijklmnopqrstuvwxyz234567', 1 + floor(random() * 32)::int, 1), '')
+            INTO candidate
+            FROM generate_series(1, 10);
+            EXIT WHEN NOT EXISTS (SELECT 1 FROM forums WHERE public_id = candidate);
+        END LOOP;
+        UPDATE forums SET public_id = candidate WHERE id = forum.id;
+    END LOOP;
+END
+$$;
+
+ALTER TABLE forums ALTER COLUMN public_id SET NOT NULL;
+ALTER TABLE forums ADD CONSTRAINT forums_public_id_key UNIQUE (public_id);
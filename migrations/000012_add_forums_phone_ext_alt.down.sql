@@ -0,0 +1,5 @@
+-- Filename: migrations/000012_add_forums_phone_ext_alt.down.sql
+
+ALTER TABLE forums DROP CONSTRAINT IF EXISTS phone_ext_check;
+ALTER TABLE forums DROP COLUMN IF EXISTS phone_alt;
+ALTER TABLE forums DROP COLUMN IF EXISTS phone_ext;
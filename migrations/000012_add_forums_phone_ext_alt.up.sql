@@ -0,0 +1,6 @@
+-- Filename: migrations/000012_add_forums_phone_ext_alt.up.sql
+
+-- The existing phone column stays as the primary number
+ALTER TABLE forums ADD COLUMN IF NOT EXISTS phone_ext text NOT NULL DEFAULT '';
+ALTER TABLE forums ADD COLUMN IF NOT EXISTS phone_alt text NOT NULL DEFAULT '';
+ALTER TABLE forums ADD CONSTRAINT phone_ext_check CHECK (phone_ext ~ '^[0-9]{0,10}$');
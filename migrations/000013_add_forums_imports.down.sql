@@ -0,0 +1,6 @@
+-- Filename: migrations/000013_add_forums_imports.down.sql
+
+DROP TABLE IF EXISTS admin_jobs;
+DROP INDEX IF EXISTS forums_external_ref_idx;
+ALTER TABLE forums DROP COLUMN IF EXISTS import_missing_since;
+ALTER TABLE forums DROP COLUMN IF EXISTS external_ref;
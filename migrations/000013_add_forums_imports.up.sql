@@ -0,0 +1,20 @@
+-- Filename: migrations/000013_add_forums_imports.up.sql
+
+-- external_ref is the registry's licence number for forums that came from
+-- an import. import_missing_since is set while the registry no longer
+-- lists the forum, for an admin to review. Imports never delete forums
+ALTER TABLE forums ADD COLUMN IF NOT EXISTS external_ref text;
+ALTER TABLE forums ADD COLUMN IF NOT EXISTS import_missing_since timestamp(0) with time zone;
+CREATE UNIQUE INDEX IF NOT EXISTS forums_external_ref_idx ON forums (external_ref) WHERE external_ref IS NOT NULL;
+
+-- One row per run of a background job, with what it did. A job is
+-- recorded when it starts, so finished_at stays null while it runs
+CREATE TABLE IF NOT EXISTS admin_jobs (
+    id bigserial PRIMARY KEY,
+    kind text NOT NULL,
+    started_at timestamp(0) with time zone NOT NULL,
+    finished_at timestamp(0) with time zone,
+    summary jsonb NOT NULL DEFAULT '{}',
+    error text NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS admin_jobs_kind_idx ON admin_jobs (kind, started_at DESC);
@@ -0,0 +1,12 @@
+-- Filename: migrations/000014_add_forums_unaccent.down.sql
+
+DROP INDEX IF EXISTS forums_name_idx;
+DROP INDEX IF EXISTS forums_level_idx;
+CREATE INDEX IF NOT EXISTS forums_name_idx ON forums USING GIN(to_tsvector('simple', name));
+CREATE INDEX IF NOT EXISTS forums_level_idx ON forums USING GIN(to_tsvector('simple', level));
+DROP TEXT SEARCH CONFIGURATION IF EXISTS simple_unaccent;
+
+DROP INDEX IF EXISTS forums_name_sort_idx;
+ALTER TABLE forums DROP COLUMN IF EXISTS name_sort;
+DROP FUNCTION IF EXISTS forum_unaccent(text);
+DROP EXTENSION IF EXISTS unaccent;
@@ -0,0 +1,32 @@
+-- Filename: migrations/000014_add_forums_unaccent.up.sql
+
+CREATE EXTENSION IF NOT EXISTS unaccent;
+
+-- unaccent() is only STABLE because its dictionary could be changed, so
+-- it can't be used in an index or generated column. Naming the dictionary
+-- pins it, which makes this wrapper safe to declare IMMUTABLE
+CREATE OR REPLACE FUNCTION forum_unaccent(text) RETURNS text
+    LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT
+    AS $$ SELECT public.unaccent('public.unaccent'::regdictionary, $1) $$;
+
+-- Names sort on their unaccented lowercase form so "Ábrego" comes before
+-- "Zion" rather than after it
+ALTER TABLE forums ADD COLUMN IF NOT EXISTS name_sort text
+    GENERATED ALWAYS AS (lower(forum_unaccent(name))) STORED;
+CREATE INDEX IF NOT EXISTS forums_name_sort_idx ON forums (name_sort);
+
+-- The simple configuration with the accents stripped, so searching
+-- "jose" finds "José"
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_ts_config WHERE cfgname = 'simple_unaccent') THEN
+        CREATE TEXT SEARCH CONFIGURATION simple_unaccent (COPY = simple);
+        ALTER TEXT SEARCH CONFIGURATION simple_unaccent
+            ALTER MAPPING FOR hword, hword_part, word WITH unaccent, simple;
+    END IF;
+END $$;
+
+DROP INDEX IF EXISTS forums_name_idx;
+DROP INDEX IF EXISTS forums_level_idx;
+CREATE INDEX IF NOT EXISTS forums_name_idx ON forums USING GIN(to_tsvector('simple_unaccent', name));
+CREATE INDEX IF NOT EXISTS forums_level_idx ON forums USING GIN(to_tsvector('simple_unaccent', level));
@@ -0,0 +1,5 @@
+-- Filename: migrations/000015_add_forums_archived.down.sql
+
+DROP INDEX IF EXISTS forums_archived_idx;
+ALTER TABLE forums DROP COLUMN IF EXISTS archive_reason;
+ALTER TABLE forums DROP COLUMN IF EXISTS archived_at;
@@ -0,0 +1,7 @@
+-- Filename: migrations/000015_add_forums_archived.up.sql
+
+-- An archived forum is closed for now but not gone. It stays readable by
+-- id and drops out of the listings until it is reopened
+ALTER TABLE forums ADD COLUMN IF NOT EXISTS archived_at timestamp(0) with time zone;
+ALTER TABLE forums ADD COLUMN IF NOT EXISTS archive_reason text NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS forums_archived_idx ON forums (archived_at) WHERE archived_at IS NOT NULL;
@@ -0,0 +1,3 @@
+-- Filename: migrations/000016_add_forum_changes_public_id.down.sql
+
+ALTER TABLE forum_changes DROP COLUMN IF EXISTS public_id;
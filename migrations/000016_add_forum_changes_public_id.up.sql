@@ -0,0 +1,8 @@
+-- Filename: migrations/000016_add_forum_changes_public_id.up.sql
+
+-- The feed names forums by public id. Changes to forums that were
+-- deleted before this migration keep a null public id
+ALTER TABLE forum_changes ADD COLUMN IF NOT EXISTS public_id text;
+UPDATE forum_changes SET public_id = forums.public_id
+FROM forums
+WHERE forums.id = forum_changes.forum_id AND forum_changes.public_id IS NULL;